@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yago-123/minikube-testing/pkg/orchestrator"
+)
+
+const (
+	defaultOlderThan = 2 * time.Hour
+	reapTimeout      = 10 * time.Minute
+)
+
+// reaper deletes the minikube, kind and k3d clusters leaked by test runs that died before cleaning up
+func main() {
+	olderThan := flag.Duration("older-than", defaultOlderThan, "delete clusters created or pool clusters idle before this duration")
+	dryRun := flag.Bool("dry-run", false, "list the clusters that would be deleted without deleting them")
+	verbose := flag.Bool("verbose", false, "print the output of minikube, kind and k3d")
+	flag.Parse()
+
+	logger := logrus.New()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	ctx, cancelTimeout := context.WithTimeout(ctx, reapTimeout)
+	defer cancelTimeout()
+
+	opts := orchestrator.ReapOptions{DryRun: *dryRun}
+	if *verbose {
+		opts.Stdout, opts.Stderr = os.Stdout, os.Stderr
+	}
+
+	reaped, err := orchestrator.Reap(ctx, *olderThan, opts)
+	for _, cluster := range reaped {
+		if *dryRun {
+			logger.Infof("%s cluster %s would be deleted with images %v", cluster.Backend, cluster.Name, cluster.Images)
+			continue
+		}
+		logger.Infof("%s cluster %s deleted with images %v", cluster.Backend, cluster.Name, cluster.Images)
+	}
+
+	if err != nil {
+		logger.Errorf("unable to reap clusters: %v", err)
+		cancelTimeout()
+		cancel()
+		os.Exit(1)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/yago-123/minikube-testing/pkg/cleanup"
 	"github.com/yago-123/minikube-testing/pkg/orchestrator"
 	"github.com/yago-123/minikube-testing/pkg/runtime"
 
@@ -21,8 +22,9 @@ const (
 )
 
 const (
-	sleepTime  = 10 * time.Second
-	ctxTimeout = 5 * time.Minute
+	sleepTime      = 10 * time.Second
+	ctxTimeout     = 5 * time.Minute
+	cleanupTimeout = 2 * time.Minute
 
 	podPort = 8080
 )
@@ -33,9 +35,22 @@ func main() {
 		log.Fatalf("Error loading .env file: %v", err)
 	}
 
+	// resources are released in reverse order on normal exit, on SIGINT/SIGTERM and on fatal errors
+	registry := cleanup.NewRegistryWithTimeout(cleanupTimeout)
 	logger := logrus.New()
-
-	ctx, cancel := context.WithTimeout(context.Background(), ctxTimeout)
+	logger.ExitFunc = registry.Exit
+	defer func() {
+		if errCleanup := registry.Run(); errCleanup != nil {
+			logger.Errorf("unable to clean up: %v", errCleanup)
+		}
+	}()
+
+	// deferred after the cleanup so that it runs first, once interrupted it waits for the operations in flight to
+	// return before cleaning up
+	ctx, stop := registry.NotifyContext(context.Background())
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(ctx, ctxTimeout)
 	defer cancel()
 
 	// todo(): think about better way, probably not the best option :)
@@ -85,22 +100,32 @@ func main() {
 	if err = dock.BuildImageWithContextPath(ctx, "yagoninja/api-server-test", "0.1.0", []byte(dockerfile), "build/docker/test-pod"); err != nil {
 		logger.Fatalf("unable to build image: %v", err)
 	}
+	// the registry may have run already if the build was interrupted, the image is removed straight away then
+	err = registry.Register("image yagoninja/api-server-test:0.1.0", func(ctx context.Context) error {
+		return dock.RemoveImage(ctx, "yagoninja/api-server-test", "0.1.0")
+	})
+	if err != nil {
+		logger.Errorf("unable to register image cleanup: %v", err)
+		return
+	}
 
 	// if err = dock.PushImage(ctx, "yagoninja/api-server-test", "0.1.0"); err != nil {
 	// 	log.Fatalf("unable to push image: %w", err)
 	// }
 
+	// register before creating the cluster, a failed start can leave the profile behind
 	minikube := orchestrator.NewMinikube(os.Stdout, os.Stderr)
-	cli, err := minikube.Create(KubernetesVersion, NumberOfNodes, NumberOfCPUs, AmountOfRAMPerNode)
+	if err = registry.Register("minikube cluster", minikube.Delete); err != nil {
+		logger.Errorf("unable to register cluster cleanup: %v", err)
+		return
+	}
+
+	cli, err := minikube.Create(ctx, KubernetesVersion, NumberOfNodes, NumberOfCPUs, AmountOfRAMPerNode)
 	if err != nil {
 		logger.Fatalf("unable to create minikube cluster: %v", err)
 	}
-	defer minikube.Delete()
-
-	// todo(): add some sort of wait mechanism
-	time.Sleep(sleepTime)
 
-	err = minikube.LoadImage("yagoninja/api-server-test", "0.1.0")
+	err = minikube.LoadImage(ctx, "yagoninja/api-server-test", "0.1.0")
 	if err != nil {
 		logger.Errorf("unable to load image: %v", err)
 		return
@@ -0,0 +1,180 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the time given to all the cleanup functions together
+	DefaultTimeout = 2 * time.Minute
+	// DefaultGracePeriod is the time given to the operations in flight to return after a signal, it's longer than the
+	// grace period given to the child processes interrupted (e.g. orchestrator.ProcessGracePeriod)
+	DefaultGracePeriod = 15 * time.Second
+
+	// signalExitBase is added to the signal number to compute the exit code, following the shell convention
+	signalExitBase = 128
+)
+
+// Func releases a resource (cluster, port-forward, image, namespace...), the context expires once the timeout of the
+// registry is reached
+type Func func(ctx context.Context) error
+
+type entry struct {
+	name string
+	fn   Func
+}
+
+// Registry keeps the cleanup functions of the resources created during a run and executes them in reverse order of
+// registration exactly once, whether the run finishes normally, is interrupted by a signal or aborted by a fatal error
+type Registry struct {
+	timeout     time.Duration
+	gracePeriod time.Duration
+
+	mu      sync.Mutex
+	entries []entry
+	// ran is set once Run takes the entries, later entries are executed as soon as they are registered
+	ran bool
+
+	once sync.Once
+	err  error
+	// exit is replaced in tests, os.Exit can't be intercepted
+	exit func(code int)
+}
+
+func NewRegistry() *Registry {
+	return NewRegistryWithTimeout(DefaultTimeout)
+}
+
+func NewRegistryWithTimeout(timeout time.Duration) *Registry {
+	return &Registry{
+		timeout:     timeout,
+		gracePeriod: DefaultGracePeriod,
+		entries:     []entry{},
+		exit:        os.Exit,
+	}
+}
+
+// WithGracePeriod replaces the time given to the operations in flight to return after a signal, see NotifyContext
+func (r *Registry) WithGracePeriod(gracePeriod time.Duration) *Registry {
+	r.gracePeriod = gracePeriod
+	return r
+}
+
+// Register adds the cleanup function of a resource, the name is used to identify it in the errors. Resources can
+// still be created while the cleanup runs (e.g. by a goroutine interrupted by a signal), if the registry already ran
+// the function is executed straight away with a context bounded by the timeout of the registry and its error returned
+func (r *Registry) Register(name string, fn Func) error {
+	r.mu.Lock()
+	if !r.ran {
+		r.entries = append(r.entries, entry{name: name, fn: fn})
+		r.mu.Unlock()
+		return nil
+	}
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	if err := fn(ctx); err != nil {
+		return fmt.Errorf("error cleaning up %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Run executes the cleanup functions in reverse order of registration with a fresh context bounded by the timeout of
+// the registry. All of them are executed even if some fail, the errors are returned together. Only the first call
+// runs the functions, concurrent and later calls wait for it and return the same result
+func (r *Registry) Run() error {
+	r.once.Do(func() {
+		r.mu.Lock()
+		entries := r.entries
+		r.entries = []entry{}
+		r.ran = true
+		r.mu.Unlock()
+
+		// the context of the run could be expired or cancelled by now, use a new one
+		ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+		defer cancel()
+
+		errs := []error{}
+		for i := len(entries) - 1; i >= 0; i-- {
+			if err := entries[i].fn(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("error cleaning up %s: %w", entries[i].name, err))
+			}
+		}
+
+		r.err = errors.Join(errs...)
+	})
+
+	return r.err
+}
+
+// Exit runs the cleanup and exits with the given code. It can replace the exit function of loggers, so that fatal
+// errors (e.g. logrus Logger.ExitFunc) release the resources before exiting
+func (r *Registry) Exit(code int) {
+	_ = r.Run()
+	r.exit(code)
+}
+
+// NotifyContext returns a context that is cancelled when SIGINT or SIGTERM is received. The stop function must be
+// called once the operations using the context return (e.g. deferred in main): after a signal it runs the cleanup and
+// exits with 128 + the signal number, so that resources are not released while the operations interrupted (e.g. a
+// minikube start) are still winding down. If the operations don't return within the grace period the cleanup runs
+// anyway. Signals keep being handled after the context finishes, until the stop function is called
+func (r *Registry) NotifyContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+
+	signals := make(chan os.Signal, 1)
+	stopped := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	// exit code of the signal received, zero until then
+	var code atomic.Int32
+
+	go func() {
+		select {
+		case sig := <-signals:
+			code.Store(int32(signalExitCode(sig))) //nolint:gosec // signal exit codes are small
+			cancel()
+
+			select {
+			case <-stopped:
+				// the operations returned, the stop function exits
+			case <-time.After(r.gracePeriod):
+				r.Exit(int(code.Load()))
+			}
+		case <-stopped:
+		}
+	}()
+
+	var stopOnce sync.Once
+	return ctx, func() {
+		stopOnce.Do(func() {
+			signal.Stop(signals)
+			close(stopped)
+		})
+		cancel()
+
+		if c := code.Load(); c != 0 {
+			r.Exit(int(c))
+		}
+	}
+}
+
+// signalExitCode returns the exit code used by shells for processes terminated by the signal
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return signalExitBase + int(s)
+	}
+
+	return 1
+}
@@ -0,0 +1,145 @@
+package cleanup //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryRun(t *testing.T) {
+	registry := NewRegistryWithTimeout(time.Second)
+
+	order := []string{}
+	for _, name := range []string{"image", "cluster", "namespace"} {
+		_ = registry.Register(name, func(ctx context.Context) error {
+			_, hasDeadline := ctx.Deadline()
+			require.True(t, hasDeadline)
+
+			order = append(order, name)
+			if name == "cluster" {
+				return errors.New("cluster not found")
+			}
+			return nil
+		})
+	}
+
+	err := registry.Run()
+	require.ErrorContains(t, err, "error cleaning up cluster")
+	require.Equal(t, []string{"namespace", "cluster", "image"}, order)
+
+	// the functions only run once
+	require.Equal(t, err, registry.Run())
+	require.Len(t, order, 3)
+}
+
+func TestRegistryRegisterAfterRun(t *testing.T) {
+	registry := NewRegistryWithTimeout(time.Second)
+	require.NoError(t, registry.Run())
+
+	// resources created while the cleanup runs are released straight away
+	cleaned := false
+	require.NoError(t, registry.Register("port-forward", func(ctx context.Context) error {
+		_, hasDeadline := ctx.Deadline()
+		require.True(t, hasDeadline)
+
+		cleaned = true
+		return nil
+	}))
+	require.True(t, cleaned)
+
+	err := registry.Register("namespace", func(_ context.Context) error {
+		return errors.New("namespace not found")
+	})
+	require.ErrorContains(t, err, "error cleaning up namespace")
+	require.NoError(t, registry.Run())
+}
+
+func TestRegistryExit(t *testing.T) {
+	registry := NewRegistry()
+
+	code, cleaned := 0, false
+	registry.exit = func(c int) { code = c }
+	require.NoError(t, registry.Register("cluster", func(_ context.Context) error {
+		cleaned = true
+		return nil
+	}))
+
+	registry.Exit(1)
+	require.True(t, cleaned)
+	require.Equal(t, 1, code)
+}
+
+func TestRegistryNotifyContext(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals can't be sent to the current process in windows")
+	}
+
+	registry := NewRegistry()
+
+	exited := make(chan int, 1)
+	registry.exit = func(c int) { exited <- c }
+
+	cleaned := false
+	require.NoError(t, registry.Register("cluster", func(_ context.Context) error {
+		cleaned = true
+		return nil
+	}))
+
+	ctx, stop := registry.NotifyContext(context.Background())
+
+	process, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, process.Signal(syscall.SIGTERM))
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context not cancelled by the signal")
+	}
+
+	// the operations in flight are still returning, the cleanup must wait for them
+	select {
+	case <-exited:
+		t.Fatal("cleanup triggered before the operations returned")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.False(t, cleaned)
+
+	stop()
+	require.Equal(t, signalExitBase+int(syscall.SIGTERM), <-exited)
+	require.True(t, cleaned)
+}
+
+func TestRegistryNotifyContextGracePeriod(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals can't be sent to the current process in windows")
+	}
+
+	registry := NewRegistry().WithGracePeriod(100 * time.Millisecond)
+
+	exited := make(chan int, 1)
+	registry.exit = func(c int) { exited <- c }
+
+	ctx, stop := registry.NotifyContext(context.Background())
+	defer stop()
+
+	process, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, process.Signal(syscall.SIGINT))
+
+	// the operations never return, the cleanup runs once the grace period expires
+	select {
+	case code := <-exited:
+		require.Equal(t, signalExitBase+int(syscall.SIGINT), code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("cleanup not triggered after the grace period")
+	}
+
+	require.Error(t, ctx.Err())
+}
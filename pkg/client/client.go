@@ -8,7 +8,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"k8s.io/client-go/discovery"
@@ -51,20 +53,35 @@ type Client interface {
 	CurlPod(ctx context.Context, pod *v1.Pod, podPort uint, path string) (*http.Response, error)
 	CurlService(ctx context.Context, url string) error
 
-	ClientSet() *kubernetes.Clientset
+	WaitForReadiness(ctx context.Context, readiness Readiness) error
+	WaitForPodsReady(ctx context.Context, namespace, labelSelector string) error
+
+	CollectDiagnostics(ctx context.Context, dir string, opts DiagnosticsOptions) error
+
+	ClientSet() kubernetes.Interface
 }
 
 type K8sClient struct {
-	cs        *kubernetes.Clientset
+	cs        kubernetes.Interface
 	dynClient *dynamic.DynamicClient
 	config    *rest.Config
 
 	settings     *cli.EnvSettings
 	actionConfig *action.Configuration
+
+	// namespaces the client deployed into or created, they scope the diagnostics by default
+	mu         sync.Mutex
+	namespaces []string
 }
 
 func NewClient() (*K8sClient, error) {
-	config, err := loadKubeConfig()
+	return NewClientWithKubeconfig(defaultKubeConfigPath(), "")
+}
+
+// NewClientWithKubeconfig creates a client bound to the given kubeconfig file and context, the current context of
+// the kubeconfig is used if kubeContext is empty
+func NewClientWithKubeconfig(kubeconfigPath, kubeContext string) (*K8sClient, error) {
+	config, err := loadKubeConfig(kubeconfigPath, kubeContext)
 	if err != nil {
 		return nil, fmt.Errorf("error loading kubeconfig: %w", err)
 	}
@@ -81,7 +98,11 @@ func NewClient() (*K8sClient, error) {
 		return nil, fmt.Errorf("error initializing dynamic client: %w", err)
 	}
 
+	// bind helm to the same kubeconfig and context, otherwise it would use the current context of ${HOME}/.kube/config
 	settings := cli.New()
+	settings.KubeConfig = kubeconfigPath
+	settings.KubeContext = kubeContext
+
 	actionConfig := new(action.Configuration)
 	if err = actionConfig.Init(settings.RESTClientGetter(), settings.Namespace(), os.Getenv(HelmDriverEnvVariable), nil); err != nil {
 		return nil, fmt.Errorf("error initializing action config: %w", err)
@@ -126,6 +147,9 @@ func (c *K8sClient) DeployHelmWithLocalChart(chartPath string, ns, release strin
 	install.ReleaseName = release
 	install.Namespace = ns
 
+	// tracked before the installation, failed releases are the ones worth diagnosing
+	c.trackNamespace(ns)
+
 	// install the chart
 	_, err = install.Run(chart, args)
 	if err != nil {
@@ -169,6 +193,13 @@ func (c *K8sClient) RunYAML(ctx context.Context, yamlManifest []byte) error {
 		namespace = DefaultNamespace
 	}
 
+	// tracked before the creation, failed deployments are the ones worth diagnosing
+	if obj.GroupVersionKind().GroupKind() == v1.SchemeGroupVersion.WithKind("Namespace").GroupKind() {
+		c.trackNamespace(obj.GetName())
+	} else {
+		c.trackNamespace(namespace)
+	}
+
 	// create the resource
 	_, err = c.dynClient.
 		Resource(gvr).
@@ -182,7 +213,7 @@ func (c *K8sClient) RunYAML(ctx context.Context, yamlManifest []byte) error {
 }
 
 func (c *K8sClient) CurlPod(ctx context.Context, pod *v1.Pod, podPort uint, path string) (*http.Response, error) {
-	stopChan, err := c.portForward(ctx, pod.Namespace, pod.Name, podPort)
+	stopChan, _, err := c.portForward(ctx, pod.Namespace, pod.Name, PortForwardLocal, podPort)
 	if err != nil {
 		return nil, fmt.Errorf("error setting up port forwarding: %w", err)
 	}
@@ -196,12 +227,30 @@ func (c *K8sClient) CurlService(ctx context.Context, url string) error {
 	return nil
 }
 
-func (c *K8sClient) ClientSet() *kubernetes.Clientset {
+func (c *K8sClient) ClientSet() kubernetes.Interface {
 	return c.cs
 }
 
-// loadKubeConfig loads the kubeconfig from ${HOME}/.kube/config
-func loadKubeConfig() (*rest.Config, error) {
+// defaultKubeConfigPath returns the path of the kubeconfig in ${HOME}/.kube/config
+// trackNamespace records a namespace the client deployed into or created
+func (c *K8sClient) trackNamespace(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if namespace != "" && !slices.Contains(c.namespaces, namespace) {
+		c.namespaces = append(c.namespaces, namespace)
+	}
+}
+
+// trackedNamespaces returns the namespaces the client deployed into or created, in the order they were first used
+func (c *K8sClient) trackedNamespaces() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.namespaces)
+}
+
+func defaultKubeConfigPath() string {
 	// access kubeconfig file
 	kubeconfigPath := ""
 	if home := homedir.HomeDir(); home != "" {
@@ -210,10 +259,17 @@ func loadKubeConfig() (*rest.Config, error) {
 		kubeconfigPath = os.Getenv("KUBECONFIG")
 	}
 
-	// building config from flags
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	return kubeconfigPath
+}
+
+// loadKubeConfig loads the kubeconfig from the given path using the given context
+func loadKubeConfig(kubeconfigPath, kubeContext string) (*rest.Config, error) {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	).ClientConfig()
 	if err != nil {
-		return nil, fmt.Errorf("error building config from flags: %w", err)
+		return nil, fmt.Errorf("error building config from %s: %w", kubeconfigPath, err)
 	}
 
 	return config, nil
@@ -264,12 +320,13 @@ func (c *K8sClient) retrieveLogsFromPod(ctx context.Context, pod *v1.Pod) ([]byt
 	return buf.Bytes(), nil
 }
 
-// portForward sets up port forwarding to the pod
-func (c *K8sClient) portForward(ctx context.Context, namespace, podName string, podPort uint) (chan struct{}, error) {
+// portForward sets up port forwarding to the pod, if localPort is 0 a random port is picked. Returns the channel
+// that stops the forwarding together with the ports forwarded
+func (c *K8sClient) portForward(ctx context.Context, namespace, podName string, localPort, podPort uint) (chan struct{}, []portforward.ForwardedPort, error) {
 	// create a round tripper
 	roundTripper, upgrader, err := spdy.RoundTripperFor(c.config)
 	if err != nil {
-		return nil, fmt.Errorf("error creating round tripper: %w", err)
+		return nil, nil, fmt.Errorf("error creating round tripper: %w", err)
 	}
 
 	// build URL for port forwarding
@@ -288,7 +345,7 @@ func (c *K8sClient) portForward(ctx context.Context, namespace, podName string,
 	out, errOut := new(strings.Builder), new(strings.Builder)
 
 	// specify the ports to forward
-	ports := []string{fmt.Sprintf("%d:%d", PortForwardLocal, podPort)}
+	ports := []string{fmt.Sprintf("%d:%d", localPort, podPort)}
 
 	// create a channel to capture errors from the goroutine
 	errChan := make(chan error, 1)
@@ -296,7 +353,7 @@ func (c *K8sClient) portForward(ctx context.Context, namespace, podName string,
 	// create the port forwarder
 	pf, err := portforward.New(dialer, ports, stopChan, readyChan, out, errOut)
 	if err != nil {
-		return nil, fmt.Errorf("error setting up port forwarding: %w", err)
+		return nil, nil, fmt.Errorf("error setting up port forwarding: %w", err)
 	}
 
 	// start port forwarding in a separate goroutine
@@ -312,17 +369,22 @@ func (c *K8sClient) portForward(ctx context.Context, namespace, podName string,
 	case <-ctx.Done():
 		// context is done, stop port forwarding
 		close(stopChan)
-		return nil, ctx.Err()
+		return nil, nil, ctx.Err()
 	case <-time.After(PortForwardTimeout):
 		// timeout occurred, stop port forwarding
 		close(stopChan)
-		return nil, fmt.Errorf("port forwarding timed out after %v", PortForwardTimeout)
+		return nil, nil, fmt.Errorf("port forwarding timed out after %v", PortForwardTimeout)
 	case errC := <-errChan:
 		// port forwarding encountered an error
-		return nil, fmt.Errorf("error starting port forwarding: %w", errC)
+		return nil, nil, fmt.Errorf("error starting port forwarding: %w", errC)
 	case <-readyChan:
 		// port forwarding is ready
-		return stopChan, nil
+		forwarded, errPorts := pf.GetPorts()
+		if errPorts != nil {
+			close(stopChan)
+			return nil, nil, fmt.Errorf("error retrieving forwarded ports: %w", errPorts)
+		}
+		return stopChan, forwarded, nil
 	}
 }
 
@@ -0,0 +1,317 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"sigs.k8s.io/yaml"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+const (
+	diagnosticsNodesDir = "nodes"
+	// diagnosticsNodeKind is the kind of the objects involved in the events of the nodes
+	diagnosticsNodeKind = "Node"
+	diagnosticsPodsDir  = "pods"
+	diagnosticsLogsDir  = "logs"
+	diagnosticsEvents   = "events.yaml"
+	diagnosticsHelm     = "helm-releases.yaml"
+)
+
+// helmReleaseStatus contains the status of a helm release as stored in the diagnostics bundle
+type helmReleaseStatus struct {
+	Name         string    `json:"name"`
+	Namespace    string    `json:"namespace"`
+	Revision     int       `json:"revision"`
+	Chart        string    `json:"chart"`
+	ChartVersion string    `json:"chartVersion"`
+	Status       string    `json:"status"`
+	LastDeployed time.Time `json:"lastDeployed"`
+	Description  string    `json:"description"`
+}
+
+// DiagnosticsOptions selects the namespaces collected by CollectDiagnostics
+type DiagnosticsOptions struct {
+	// Namespaces are collected instead of the ones the client deployed into or created
+	Namespaces []string
+	// AllNamespaces collects every namespace of the cluster, including the system ones, and overrides Namespaces
+	AllNamespaces bool
+}
+
+// CollectDiagnostics writes the state of the cluster into dir so that failures can be debugged after the cluster is
+// gone: the nodes and, for each namespace, the events, the pods, the current and previous logs of their containers
+// and the status of the helm releases. By default the namespaces are the ones the client deployed into (RunYAML and
+// helm installs) or created. Collection is best effort, everything that can be collected is written and the errors
+// are returned together
+func (c *K8sClient) CollectDiagnostics(ctx context.Context, dir string, opts DiagnosticsOptions) error {
+	namespaces, err := c.diagnosticsNamespaces(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{c.collectNodes(ctx, filepath.Join(dir, diagnosticsNodesDir))}
+	for _, ns := range namespaces {
+		errs = append(errs,
+			c.collectEvents(ctx, ns, filepath.Join(dir, ns, diagnosticsEvents)),
+			c.collectPods(ctx, ns, filepath.Join(dir, ns)),
+			c.collectHelmReleases(ns, filepath.Join(dir, ns, diagnosticsHelm)),
+		)
+	}
+
+	return errors.Join(errs...)
+}
+
+// diagnosticsNamespaces returns the namespaces selected by the options
+func (c *K8sClient) diagnosticsNamespaces(ctx context.Context, opts DiagnosticsOptions) ([]string, error) {
+	if !opts.AllNamespaces {
+		if len(opts.Namespaces) > 0 {
+			return opts.Namespaces, nil
+		}
+
+		return c.trackedNamespaces(), nil
+	}
+
+	nsList, err := c.cs.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return []string{}, fmt.Errorf("error listing namespaces: %w", err)
+	}
+
+	namespaces := []string{}
+	for _, ns := range nsList.Items {
+		namespaces = append(namespaces, ns.Name)
+	}
+
+	return namespaces, nil
+}
+
+// collectNodes writes the YAML of each node together with a summary in the style of kubectl describe with its
+// conditions, its capacity against the allocatable resources and its events
+func (c *K8sClient) collectNodes(ctx context.Context, dir string) error {
+	nodes, err := c.cs.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing nodes: %w", err)
+	}
+
+	errs := []error{}
+
+	// the descriptions are written without events if they can't be listed
+	events, err := c.cs.CoreV1().Events(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("involvedObject.kind", diagnosticsNodeKind).String(),
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error listing events of nodes: %w", err))
+		events = &v1.EventList{}
+	}
+
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		node.ManagedFields = nil
+		errs = append(errs, writeYAML(filepath.Join(dir, fmt.Sprintf("%s.yaml", node.Name)), node))
+
+		nodeEvents := slices.DeleteFunc(slices.Clone(events.Items), func(event v1.Event) bool {
+			return event.InvolvedObject.Kind != diagnosticsNodeKind || event.InvolvedObject.Name != node.Name
+		})
+		errs = append(errs, writeFile(filepath.Join(dir, fmt.Sprintf("%s.describe.txt", node.Name)), describeNode(node, nodeEvents)))
+	}
+
+	return errors.Join(errs...)
+}
+
+// describeNode summarizes the node in the style of kubectl describe
+func describeNode(node *v1.Node, events []v1.Event) []byte {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Name:\t%s\n", node.Name)
+	fmt.Fprintf(w, "Unschedulable:\t%t\n", node.Spec.Unschedulable)
+
+	fmt.Fprintf(w, "Conditions:\n  TYPE\tSTATUS\tREASON\tLAST TRANSITION\tMESSAGE\n")
+	for _, cond := range node.Status.Conditions {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", cond.Type, cond.Status, cond.Reason, cond.LastTransitionTime.UTC().Format(time.RFC3339), cond.Message)
+	}
+
+	resources := []string{}
+	for name := range node.Status.Capacity {
+		resources = append(resources, string(name))
+	}
+	for name := range node.Status.Allocatable {
+		if _, found := node.Status.Capacity[name]; !found {
+			resources = append(resources, string(name))
+		}
+	}
+	slices.Sort(resources)
+
+	fmt.Fprintf(w, "Resources:\n  RESOURCE\tCAPACITY\tALLOCATABLE\n")
+	for _, name := range resources {
+		capacity, allocatable := node.Status.Capacity[v1.ResourceName(name)], node.Status.Allocatable[v1.ResourceName(name)]
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", name, capacity.String(), allocatable.String())
+	}
+
+	if len(events) == 0 {
+		fmt.Fprintf(w, "Events:\t<none>\n")
+	} else {
+		fmt.Fprintf(w, "Events:\n  TYPE\tREASON\tCOUNT\tLAST SEEN\tMESSAGE\n")
+		for _, event := range events {
+			fmt.Fprintf(w, "  %s\t%s\t%d\t%s\t%s\n", event.Type, event.Reason, event.Count, event.LastTimestamp.UTC().Format(time.RFC3339), event.Message)
+		}
+	}
+
+	_ = w.Flush()
+	return []byte(sb.String())
+}
+
+// collectEvents writes all the events of the namespace
+func (c *K8sClient) collectEvents(ctx context.Context, namespace, path string) error {
+	events, err := c.cs.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing events of namespace %s: %w", namespace, err)
+	}
+
+	for i := range events.Items {
+		events.Items[i].ManagedFields = nil
+	}
+
+	return writeYAML(path, events.Items)
+}
+
+// collectPods writes the YAML of each pod of the namespace together with the logs of its containers. Previous logs
+// are only retrieved for containers that restarted, the rest don't have any
+func (c *K8sClient) collectPods(ctx context.Context, namespace, dir string) error {
+	pods, err := c.cs.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing pods of namespace %s: %w", namespace, err)
+	}
+
+	errs := []error{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		pod.ManagedFields = nil
+		errs = append(errs, writeYAML(filepath.Join(dir, diagnosticsPodsDir, fmt.Sprintf("%s.yaml", pod.Name)), pod))
+
+		statuses := append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+
+		logsDir := filepath.Join(dir, diagnosticsLogsDir, pod.Name)
+		for _, status := range statuses {
+			// containers waiting to start don't have current logs
+			if status.State.Waiting == nil {
+				errs = append(errs, c.collectContainerLogs(ctx, pod, status.Name, false, logsDir))
+			}
+			if status.RestartCount > 0 {
+				errs = append(errs, c.collectContainerLogs(ctx, pod, status.Name, true, logsDir))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// collectContainerLogs writes the logs of the container, or the ones of its previous instance
+func (c *K8sClient) collectContainerLogs(ctx context.Context, pod *v1.Pod, container string, previous bool, dir string) error {
+	name := fmt.Sprintf("%s.log", container)
+	if previous {
+		name = fmt.Sprintf("%s.previous.log", container)
+	}
+
+	req := c.cs.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &v1.PodLogOptions{Container: container, Previous: previous})
+	logs, err := req.Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("error retrieving logs of %s/%s container %s: %w", pod.Namespace, pod.Name, container, err)
+	}
+	defer logs.Close()
+
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error creating directory %s: %w", dir, err)
+	}
+
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return fmt.Errorf("error creating log file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err = io.Copy(f, logs); err != nil {
+		return fmt.Errorf("error writing logs of %s/%s container %s: %w", pod.Namespace, pod.Name, container, err)
+	}
+
+	return nil
+}
+
+// collectHelmReleases writes the status of all the helm releases of the namespace, including the failed ones
+func (c *K8sClient) collectHelmReleases(namespace, path string) error {
+	// helm storage is scoped to a namespace, the action config of the client only sees the default one
+	actionConfig := new(action.Configuration)
+	if err := actionConfig.Init(c.settings.RESTClientGetter(), namespace, os.Getenv(HelmDriverEnvVariable), nil); err != nil {
+		return fmt.Errorf("error initializing action config for namespace %s: %w", namespace, err)
+	}
+
+	return writeHelmReleases(actionConfig, namespace, path)
+}
+
+// writeHelmReleases writes the status of the releases stored in the action config, nothing is written if there are
+// none
+func writeHelmReleases(actionConfig *action.Configuration, namespace, path string) error {
+	list := action.NewList(actionConfig)
+	list.All = true
+	list.SetStateMask()
+
+	releases, err := list.Run()
+	if err != nil {
+		return fmt.Errorf("error listing helm releases of namespace %s: %w", namespace, err)
+	}
+
+	if len(releases) == 0 {
+		return nil
+	}
+
+	statuses := []helmReleaseStatus{}
+	for _, rel := range releases {
+		status := helmReleaseStatus{Name: rel.Name, Namespace: rel.Namespace, Revision: rel.Version}
+		if rel.Chart != nil && rel.Chart.Metadata != nil {
+			status.Chart, status.ChartVersion = rel.Chart.Metadata.Name, rel.Chart.Metadata.Version
+		}
+		if rel.Info != nil {
+			status.Status = rel.Info.Status.String()
+			status.LastDeployed = rel.Info.LastDeployed.Time
+			status.Description = rel.Info.Description
+		}
+		statuses = append(statuses, status)
+	}
+
+	return writeYAML(path, statuses)
+}
+
+// writeYAML serializes the object into the file, creating the parent directories if needed
+func writeYAML(path string, obj interface{}) error {
+	content, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("error serializing %s: %w", filepath.Base(path), err)
+	}
+
+	return writeFile(path, content)
+}
+
+// writeFile writes the content into the file, creating the parent directories if needed
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating directory %s: %w", filepath.Dir(path), err)
+	}
+
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package client //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/cli"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestWriteYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apps", diagnosticsHelm)
+
+	statuses := []helmReleaseStatus{{Name: "api", Namespace: "apps", Revision: 2, Chart: "api", ChartVersion: "0.1.0", Status: "failed"}}
+	require.NoError(t, writeYAML(path, statuses))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "status: failed")
+	require.Contains(t, string(content), "chartVersion: 0.1.0")
+}
+
+func TestCollectPods(t *testing.T) {
+	dir := t.TempDir()
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "apps", ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}},
+		Status: v1.PodStatus{
+			InitContainerStatuses: []v1.ContainerStatus{
+				{Name: "migrate", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 0}}},
+			},
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: "app", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+				{Name: "crash", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}, RestartCount: 3},
+				{Name: "sidecar", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}, RestartCount: 1},
+				{Name: "pending", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}}},
+			},
+		},
+	}
+	c := &K8sClient{cs: fake.NewSimpleClientset(pod)}
+
+	require.NoError(t, c.collectPods(context.Background(), "apps", dir))
+
+	content, err := os.ReadFile(filepath.Join(dir, diagnosticsPodsDir, "api.yaml"))
+	require.NoError(t, err)
+	require.Contains(t, string(content), "name: api")
+	require.NotContains(t, string(content), "managedFields")
+
+	// the previous logs are only requested for containers that restarted, the rest have no earlier instance. The
+	// current logs are skipped for containers waiting to start
+	logs, err := os.ReadDir(filepath.Join(dir, diagnosticsLogsDir, "api"))
+	require.NoError(t, err)
+	names := []string{}
+	for _, entry := range logs {
+		names = append(names, entry.Name())
+	}
+	require.ElementsMatch(t, []string{"migrate.log", "app.log", "crash.previous.log", "sidecar.log", "sidecar.previous.log"}, names)
+}
+
+func TestCollectNodes(t *testing.T) {
+	dir := t.TempDir()
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{
+				{Type: v1.NodeMemoryPressure, Status: v1.ConditionTrue, Reason: "KubeletHasInsufficientMemory"},
+				{Type: v1.NodeReady, Status: v1.ConditionFalse, Reason: "KubeletNotReady", Message: "container runtime is down"},
+			},
+			Capacity:    v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("2Gi")},
+			Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1900m"), v1.ResourceMemory: resource.MustParse("1800Mi")},
+		},
+	}
+	event := func(name, kind, object, reason string) *v1.Event {
+		return &v1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: DefaultNamespace},
+			InvolvedObject: v1.ObjectReference{Kind: kind, Name: object},
+			Type:           v1.EventTypeWarning,
+			Reason:         reason,
+			Count:          2,
+		}
+	}
+	c := &K8sClient{cs: fake.NewSimpleClientset(
+		node,
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+		event("node-1.1", diagnosticsNodeKind, "node-1", "NodeNotReady"),
+		event("node-3.1", diagnosticsNodeKind, "node-3", "RegisteredNode"),
+		event("node-1.2", "Pod", "node-1", "BackOff"),
+	)}
+
+	require.NoError(t, c.collectNodes(context.Background(), dir))
+	require.FileExists(t, filepath.Join(dir, "node-1.yaml"))
+
+	content, err := os.ReadFile(filepath.Join(dir, "node-1.describe.txt"))
+	require.NoError(t, err)
+	require.Regexp(t, `Ready\s+False\s+KubeletNotReady\s+\S+\s+container runtime is down`, string(content))
+	require.Regexp(t, `cpu\s+2\s+1900m`, string(content))
+	require.Regexp(t, `memory\s+2Gi\s+1800Mi`, string(content))
+	require.Regexp(t, `Warning\s+NodeNotReady\s+2`, string(content))
+	require.NotContains(t, string(content), "RegisteredNode")
+	require.NotContains(t, string(content), "BackOff")
+
+	content, err = os.ReadFile(filepath.Join(dir, "node-2.describe.txt"))
+	require.NoError(t, err)
+	require.Regexp(t, `Events:\s+<none>`, string(content))
+}
+
+func TestCollectEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apps", diagnosticsEvents)
+	events := []runtime.Object{
+		&v1.Event{ObjectMeta: metav1.ObjectMeta{Name: "api.1", Namespace: "apps"}, Reason: "BackOff", Message: "Back-off restarting failed container"},
+		&v1.Event{ObjectMeta: metav1.ObjectMeta{Name: "dns.1", Namespace: SystemNamespace}, Reason: "Pulled"},
+	}
+	c := &K8sClient{cs: fake.NewSimpleClientset(events...)}
+
+	require.NoError(t, c.collectEvents(context.Background(), "apps", path))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "reason: BackOff")
+	require.NotContains(t, string(content), "reason: Pulled")
+}
+
+func TestWriteHelmReleases(t *testing.T) {
+	actionConfig := &action.Configuration{
+		Releases:   storage.Init(driver.NewMemory()),
+		KubeClient: &kubefake.PrintingKubeClient{Out: io.Discard},
+		Log:        func(string, ...interface{}) {},
+	}
+
+	path := filepath.Join(t.TempDir(), "apps", diagnosticsHelm)
+	require.NoError(t, writeHelmReleases(actionConfig, "apps", path))
+	require.NoFileExists(t, path)
+
+	for _, rel := range []*release.Release{
+		{Name: "api", Namespace: "apps", Version: 1, Info: &release.Info{Status: release.StatusSuperseded}},
+		{Name: "api", Namespace: "apps", Version: 2, Info: &release.Info{Status: release.StatusFailed, Description: "timed out"}},
+	} {
+		rel.Chart = &chart.Chart{Metadata: &chart.Metadata{Name: "api", Version: "0.1.0"}}
+		require.NoError(t, actionConfig.Releases.Create(rel))
+	}
+
+	// failed releases are included, they are the ones worth debugging
+	require.NoError(t, writeHelmReleases(actionConfig, "apps", path))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "status: failed")
+	require.Contains(t, string(content), "description: timed out")
+	require.Contains(t, string(content), "chartVersion: 0.1.0")
+}
+
+func TestCollectDiagnosticsJoinsErrors(t *testing.T) {
+	t.Setenv(HelmDriverEnvVariable, "")
+	dir := t.TempDir()
+
+	cs := fake.NewSimpleClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "apps"}},
+	)
+	cs.PrependReactor("list", "events", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("events unavailable")
+	})
+
+	// helm can't reach any cluster with a missing kubeconfig
+	settings := cli.New()
+	settings.KubeConfig = filepath.Join(dir, "missing-kubeconfig")
+	c := &K8sClient{cs: cs, settings: settings}
+
+	// the collectors that fail don't prevent the rest from writing their files
+	err := c.CollectDiagnostics(context.Background(), dir, DiagnosticsOptions{Namespaces: []string{"apps"}})
+	require.ErrorContains(t, err, "error listing events of namespace apps: events unavailable")
+	require.ErrorContains(t, err, "error listing helm releases of namespace apps")
+	require.ErrorContains(t, err, "error listing events of nodes: events unavailable")
+	require.FileExists(t, filepath.Join(dir, diagnosticsNodesDir, "node-1.yaml"))
+	require.FileExists(t, filepath.Join(dir, diagnosticsNodesDir, "node-1.describe.txt"))
+	require.FileExists(t, filepath.Join(dir, "apps", diagnosticsPodsDir, "api.yaml"))
+	require.NoFileExists(t, filepath.Join(dir, "apps", diagnosticsEvents))
+}
+
+func TestDiagnosticsNamespaces(t *testing.T) {
+	cs := fake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: DefaultNamespace}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: SystemNamespace}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+	)
+	c := &K8sClient{cs: cs}
+	c.trackNamespace("apps")
+	c.trackNamespace("monitoring")
+	c.trackNamespace("apps")
+
+	tests := []struct {
+		name string
+		opts DiagnosticsOptions
+		want []string
+	}{
+		{
+			name: "Namespaces touched by the client",
+			opts: DiagnosticsOptions{},
+			want: []string{"apps", "monitoring"},
+		},
+		{
+			name: "Explicit namespaces",
+			opts: DiagnosticsOptions{Namespaces: []string{SystemNamespace}},
+			want: []string{SystemNamespace},
+		},
+		{
+			name: "All namespaces",
+			opts: DiagnosticsOptions{Namespaces: []string{SystemNamespace}, AllNamespaces: true},
+			want: []string{"apps", DefaultNamespace, SystemNamespace},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespaces, err := c.diagnosticsNamespaces(context.Background(), tt.opts)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, namespaces)
+		})
+	}
+}
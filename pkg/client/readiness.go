@@ -0,0 +1,312 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ReadinessPollInterval = 2 * time.Second
+
+	SystemNamespace       = "kube-system"
+	DefaultServiceAccount = "default"
+
+	// dnsLabelSelector selects the CoreDNS pods, the label is kept for compatibility with kube-dns
+	dnsLabelSelector = "k8s-app=kube-dns"
+	dnsPort          = 53
+	// dnsProbeName is resolved through CoreDNS to verify that cluster DNS works (trailing dot skips search domains)
+	dnsProbeName = "kubernetes.default.svc.cluster.local."
+)
+
+type ReadinessCheck string
+
+const (
+	CheckAPIServer             ReadinessCheck = "apiserver"
+	CheckNodesReady            ReadinessCheck = "nodes-ready"
+	CheckSystemPods            ReadinessCheck = "system-pods"
+	CheckDefaultServiceAccount ReadinessCheck = "default-service-account"
+	CheckDNS                   ReadinessCheck = "dns"
+)
+
+// Readiness defines the conditions that must be met for a cluster to be considered ready, checks are evaluated in
+// order and must all succeed in the same round
+type Readiness struct {
+	Checks   []ReadinessCheck
+	Interval time.Duration
+	// IgnoredNodes are not required to be Ready by CheckNodesReady (e.g. nodes stopped on purpose)
+	IgnoredNodes []string
+}
+
+// DefaultReadiness returns the readiness definition that includes all the checks available
+func DefaultReadiness() Readiness {
+	return Readiness{
+		Checks: []ReadinessCheck{
+			CheckAPIServer,
+			CheckNodesReady,
+			CheckSystemPods,
+			CheckDefaultServiceAccount,
+			CheckDNS,
+		},
+		Interval: ReadinessPollInterval,
+	}
+}
+
+// NotReadyError is returned when the cluster does not become ready before the context finishes, contains the first
+// check that was still failing
+type NotReadyError struct {
+	Check  ReadinessCheck
+	Reason error
+	Err    error
+}
+
+func (e *NotReadyError) Error() string {
+	return fmt.Sprintf("cluster not ready, check %s failed: %v: %v", e.Check, e.Reason, e.Err)
+}
+
+func (e *NotReadyError) Unwrap() error {
+	return e.Err
+}
+
+// WaitForReadiness blocks until all the checks of the readiness definition succeed or the context finishes
+func (c *K8sClient) WaitForReadiness(ctx context.Context, readiness Readiness) error {
+	interval := readiness.Interval
+	if interval == 0 {
+		interval = ReadinessPollInterval
+	}
+
+	for {
+		check, reason := c.checkReadiness(ctx, readiness)
+		if reason == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return &NotReadyError{Check: check, Reason: reason, Err: ctx.Err()}
+		case <-time.After(interval):
+		}
+	}
+}
+
+// WaitForPodsReady blocks until at least one pod matches the label selector and all the matching pods are ready
+// (pods that already completed are ignored), or until the context finishes. An empty namespace matches all of them
+func (c *K8sClient) WaitForPodsReady(ctx context.Context, namespace, labelSelector string) error {
+	for {
+		reason := c.checkPodsReady(ctx, namespace, labelSelector)
+		if reason == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("pods %s not ready: %w: %w", labelSelector, reason, ctx.Err())
+		case <-time.After(ReadinessPollInterval):
+		}
+	}
+}
+
+// checkReadiness runs the checks in order, returns the first check that fails together with the reason
+func (c *K8sClient) checkReadiness(ctx context.Context, readiness Readiness) (ReadinessCheck, error) {
+	for _, check := range readiness.Checks {
+		var err error
+
+		switch check {
+		case CheckAPIServer:
+			err = c.checkAPIServer(ctx)
+		case CheckNodesReady:
+			err = c.checkNodesReady(ctx, readiness.IgnoredNodes)
+		case CheckSystemPods:
+			err = c.checkSystemPods(ctx)
+		case CheckDefaultServiceAccount:
+			err = c.checkDefaultServiceAccount(ctx)
+		case CheckDNS:
+			err = c.checkDNS(ctx)
+		default:
+			err = fmt.Errorf("unknown readiness check")
+		}
+
+		if err != nil {
+			return check, err
+		}
+	}
+
+	return "", nil
+}
+
+// checkAPIServer verifies that the API server reports itself as ready
+func (c *K8sClient) checkAPIServer(ctx context.Context) error {
+	if _, err := c.cs.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx); err != nil {
+		return fmt.Errorf("API server not healthy: %w", err)
+	}
+
+	return nil
+}
+
+// checkNodesReady verifies that all the nodes have the Ready condition, except the ignored ones
+func (c *K8sClient) checkNodesReady(ctx context.Context, ignored []string) error {
+	nodes, err := c.cs.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing nodes: %w", err)
+	}
+
+	if len(nodes.Items) == 0 {
+		return errors.New("no nodes registered")
+	}
+
+	if notReady := notReadyNodes(nodes.Items, ignored); len(notReady) > 0 {
+		return fmt.Errorf("nodes not ready: %s", strings.Join(notReady, ", "))
+	}
+
+	return nil
+}
+
+// notReadyNodes returns the names of the nodes without the Ready condition that are not ignored
+func notReadyNodes(nodes []v1.Node, ignored []string) []string {
+	notReady := []string{}
+	for _, node := range nodes {
+		if !isNodeReady(node) && !slices.Contains(ignored, node.Name) {
+			notReady = append(notReady, node.Name)
+		}
+	}
+
+	return notReady
+}
+
+// checkSystemPods verifies that all the pods of the kube-system namespace are running
+func (c *K8sClient) checkSystemPods(ctx context.Context) error {
+	pods, err := c.cs.CoreV1().Pods(SystemNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing system pods: %w", err)
+	}
+
+	if len(pods.Items) == 0 {
+		return errors.New("no system pods scheduled")
+	}
+
+	notRunning := []string{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning && pod.Status.Phase != v1.PodSucceeded {
+			notRunning = append(notRunning, fmt.Sprintf("%s (%s)", pod.Name, pod.Status.Phase))
+		}
+	}
+
+	if len(notRunning) > 0 {
+		return fmt.Errorf("system pods not running: %s", strings.Join(notRunning, ", "))
+	}
+
+	return nil
+}
+
+// checkDefaultServiceAccount verifies that the default service account has been created, pods can't be created in
+// the default namespace until then
+func (c *K8sClient) checkDefaultServiceAccount(ctx context.Context) error {
+	_, err := c.cs.CoreV1().ServiceAccounts(DefaultNamespace).Get(ctx, DefaultServiceAccount, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("default service account not present: %w", err)
+	}
+
+	return nil
+}
+
+// checkDNS verifies that CoreDNS resolves cluster names by sending a query through a port forward to one of its pods
+func (c *K8sClient) checkDNS(ctx context.Context) error {
+	pods, err := c.cs.CoreV1().Pods(SystemNamespace).List(ctx, metav1.ListOptions{LabelSelector: dnsLabelSelector})
+	if err != nil {
+		return fmt.Errorf("error listing DNS pods: %w", err)
+	}
+
+	var dnsPod *v1.Pod
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == v1.PodRunning {
+			dnsPod = &pods.Items[i]
+			break
+		}
+	}
+
+	if dnsPod == nil {
+		return errors.New("no DNS pod running")
+	}
+
+	stopChan, ports, err := c.portForward(ctx, dnsPod.Namespace, dnsPod.Name, 0, dnsPort)
+	if err != nil {
+		return fmt.Errorf("error forwarding DNS port: %w", err)
+	}
+	defer close(stopChan)
+
+	// port forwarding only supports TCP, the resolver switches to DNS over TCP when the connection is not a packet
+	// connection
+	address := fmt.Sprintf("localhost:%d", ports[0].Local)
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "tcp", address)
+		},
+	}
+
+	if _, err = resolver.LookupHost(ctx, dnsProbeName); err != nil {
+		return fmt.Errorf("error resolving %s: %w", dnsProbeName, err)
+	}
+
+	return nil
+}
+
+// checkPodsReady verifies that at least one pod matches the selector and that all of them are ready
+func (c *K8sClient) checkPodsReady(ctx context.Context, namespace, labelSelector string) error {
+	pods, err := c.cs.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return fmt.Errorf("error listing pods: %w", err)
+	}
+
+	notReady, running := []string{}, 0
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == v1.PodSucceeded {
+			continue
+		}
+
+		running++
+		if !isPodReady(pod) {
+			notReady = append(notReady, pod.Name)
+		}
+	}
+
+	if running == 0 {
+		return errors.New("no pods scheduled")
+	}
+
+	if len(notReady) > 0 {
+		return fmt.Errorf("pods not ready: %s", strings.Join(notReady, ", "))
+	}
+
+	return nil
+}
+
+// isPodReady checks whether the pod has the Ready condition set to true
+func isPodReady(pod v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// isNodeReady checks whether the node has the Ready condition set to true
+func isNodeReady(node v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+
+	return false
+}
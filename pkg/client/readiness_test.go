@@ -0,0 +1,63 @@
+package client //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsNodeReady(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []v1.NodeCondition
+		want       bool
+	}{
+		{
+			name:       "Ready node",
+			conditions: []v1.NodeCondition{{Type: v1.NodeMemoryPressure, Status: v1.ConditionFalse}, {Type: v1.NodeReady, Status: v1.ConditionTrue}},
+			want:       true,
+		},
+		{
+			name:       "Not ready node",
+			conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}},
+			want:       false,
+		},
+		{
+			name:       "Node without conditions",
+			conditions: []v1.NodeCondition{},
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, isNodeReady(v1.Node{Status: v1.NodeStatus{Conditions: tt.conditions}}))
+		})
+	}
+}
+
+func TestNotReadyNodes(t *testing.T) {
+	node := func(name string, status v1.ConditionStatus) v1.Node {
+		return v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: status}}},
+		}
+	}
+	nodes := []v1.Node{node("node-1", v1.ConditionTrue), node("node-2", v1.ConditionFalse), node("node-3", v1.ConditionUnknown)}
+
+	require.Equal(t, []string{"node-2", "node-3"}, notReadyNodes(nodes, []string{}))
+	require.Equal(t, []string{"node-3"}, notReadyNodes(nodes, []string{"node-2"}))
+	require.Empty(t, notReadyNodes(nodes, []string{"node-2", "node-3"}))
+}
+
+func TestNotReadyError(t *testing.T) {
+	err := &NotReadyError{Check: CheckDNS, Reason: errors.New("no DNS pod running"), Err: context.DeadlineExceeded}
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Contains(t, err.Error(), string(CheckDNS))
+	require.Contains(t, err.Error(), "no DNS pod running")
+}
@@ -0,0 +1,100 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const addonStatusEnabled = "enabled"
+
+// addonWorkload identifies the pods deployed by an addon, used to wait until the addon is ready
+type addonWorkload struct {
+	namespace     string
+	labelSelector string
+}
+
+// addonWorkloads returns the workloads of the addons that can be waited for, addons not included here (e.g.
+// default-storageclass) don't deploy pods or their pods can't be identified, so they are not waited for
+func addonWorkloads() map[string]addonWorkload {
+	return map[string]addonWorkload{
+		"ingress":             {namespace: "ingress-nginx", labelSelector: "app.kubernetes.io/component=controller"},
+		"metrics-server":      {namespace: "kube-system", labelSelector: "k8s-app=metrics-server"},
+		"registry":            {namespace: "kube-system", labelSelector: "kubernetes.io/minikube-addons=registry"},
+		"csi-hostpath-driver": {namespace: "kube-system", labelSelector: "kubernetes.io/minikube-addons=csi-hostpath-driver"},
+		"storage-provisioner": {namespace: "kube-system", labelSelector: "integration-test=storage-provisioner"},
+	}
+}
+
+// Addon represents a minikube addon and whether it's enabled in the profile
+type Addon struct {
+	Name    string
+	Enabled bool
+}
+
+// addonStatus represents each of the entries of minikube addons list -o json
+type addonStatus struct {
+	Profile string `json:"Profile"`
+	Status  string `json:"Status"`
+}
+
+// ListAddons returns all the addons available in minikube sorted by name
+func (mc *Minikube) ListAddons(ctx context.Context) ([]Addon, error) {
+	out, err := mc.output(ctx, "addons", "list", "--output=json", fmt.Sprintf("--profile=%s", mc.profile))
+	if err != nil {
+		return []Addon{}, fmt.Errorf("failed to list addons: %w", err)
+	}
+
+	return parseAddons(out)
+}
+
+// EnableAddon enables the addon and blocks until its workloads are ready or the context finishes
+func (mc *Minikube) EnableAddon(ctx context.Context, name string) error {
+	if err := mc.run(ctx, "addons", "enable", name, fmt.Sprintf("--profile=%s", mc.profile)); err != nil {
+		return fmt.Errorf("failed to enable addon %s: %w", name, err)
+	}
+
+	workload, ok := addonWorkloads()[name]
+	if !ok {
+		return nil
+	}
+
+	cli, err := mc.client()
+	if err != nil {
+		return err
+	}
+
+	if err = cli.WaitForPodsReady(ctx, workload.namespace, workload.labelSelector); err != nil {
+		return fmt.Errorf("addon %s not ready: %w", name, err)
+	}
+
+	return nil
+}
+
+func (mc *Minikube) DisableAddon(ctx context.Context, name string) error {
+	if err := mc.run(ctx, "addons", "disable", name, fmt.Sprintf("--profile=%s", mc.profile)); err != nil {
+		return fmt.Errorf("failed to disable addon %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// parseAddons parses the output of minikube addons list -o json
+func parseAddons(out []byte) ([]Addon, error) {
+	statuses := map[string]addonStatus{}
+	if err := json.Unmarshal(out, &statuses); err != nil {
+		return []Addon{}, fmt.Errorf("error parsing addons: %w", err)
+	}
+
+	addons := []Addon{}
+	for name, status := range statuses {
+		addons = append(addons, Addon{Name: name, Enabled: status.Status == addonStatusEnabled})
+	}
+
+	sort.Slice(addons, func(i, j int) bool {
+		return addons[i].Name < addons[j].Name
+	})
+
+	return addons, nil
+}
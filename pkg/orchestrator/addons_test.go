@@ -0,0 +1,26 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddons(t *testing.T) {
+	out := `{
+		"ingress": {"Profile": "my-profile", "Status": "disabled"},
+		"default-storageclass": {"Profile": "my-profile", "Status": "enabled"},
+		"storage-provisioner": {"Profile": "my-profile", "Status": "enabled"}
+	}`
+
+	addons, err := parseAddons([]byte(out))
+	require.NoError(t, err)
+	require.Equal(t, []Addon{
+		{Name: "default-storageclass", Enabled: true},
+		{Name: "ingress", Enabled: false},
+		{Name: "storage-provisioner", Enabled: true},
+	}, addons)
+
+	_, err = parseAddons([]byte("* Profile not found"))
+	require.Error(t, err)
+}
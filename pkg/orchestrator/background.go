@@ -0,0 +1,103 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// backgroundProcess represents a command that keeps running after it's ready (e.g. minikube mount or minikube
+// tunnel), the process is interrupted when stopped or when the context it was started with finishes
+type backgroundProcess struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// err is the error returned by the process, only set if it exited before being stopped
+	err error
+}
+
+// startBackground starts the command in background and blocks until its output contains the ready marker. If the
+// process exits or the context finishes before being ready the process is stopped and an error is returned
+func startBackground(ctx context.Context, runner CommandRunner, cmd Command, marker string) (*backgroundProcess, error) {
+	bgCtx, cancel := context.WithCancel(ctx)
+
+	watcher := newOutputWatcher(cmd.Stdout, marker)
+	cmd.Stdout = watcher
+
+	p := &backgroundProcess{name: cmd.Name, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+
+		err := runner.Run(bgCtx, cmd)
+		// errors caused by stopping the process are expected
+		if bgCtx.Err() == nil {
+			if err == nil {
+				err = fmt.Errorf("%s exited", cmd.Name)
+			}
+			p.err = err
+		}
+	}()
+
+	select {
+	case <-watcher.found:
+		return p, nil
+	case <-p.done:
+		cancel()
+		return nil, fmt.Errorf("%s exited before being ready: %w", cmd.Name, p.err)
+	case <-ctx.Done():
+		_ = p.stop()
+		return nil, fmt.Errorf("%s not ready: %w", cmd.Name, ctx.Err())
+	}
+}
+
+// stop interrupts the process and waits until it exits, returns the error of the process if it had already exited
+// on its own. It's safe to call it more than once
+func (p *backgroundProcess) stop() error {
+	p.cancel()
+	<-p.done
+
+	return p.err
+}
+
+// exited returns a channel that is closed once the process exits
+func (p *backgroundProcess) exited() <-chan struct{} {
+	return p.done
+}
+
+// outputWatcher forwards the output of a process and signals once the output contains the marker
+type outputWatcher struct {
+	out    io.Writer
+	marker []byte
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	matched bool
+	found   chan struct{}
+}
+
+func newOutputWatcher(out io.Writer, marker string) *outputWatcher {
+	return &outputWatcher{
+		out:    nonNilWriter(out),
+		marker: []byte(marker),
+		found:  make(chan struct{}),
+	}
+}
+
+func (w *outputWatcher) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	// the output is only kept until the marker is found, background processes can run for a long time
+	if !w.matched {
+		w.buf.Write(p)
+		if bytes.Contains(w.buf.Bytes(), w.marker) {
+			w.matched = true
+			w.buf.Reset()
+			close(w.found)
+		}
+	}
+	w.mu.Unlock()
+
+	return w.out.Write(p)
+}
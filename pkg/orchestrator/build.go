@@ -0,0 +1,108 @@
+package orchestrator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yago-123/minikube-testing/pkg/runtime"
+)
+
+const (
+	dockerEnvHost     = "DOCKER_HOST"
+	dockerEnvCertPath = "DOCKER_CERT_PATH"
+
+	// buildDockerfileName is the name given to the dockerfile inside the build context sent to minikube image build
+	buildDockerfileName = "Dockerfile.minikube-testing"
+)
+
+// DockerEnv returns the endpoint of the docker daemon that runs inside the cluster, the equivalent of minikube
+// docker-env. Only available for clusters that use the docker container runtime
+func (mc *Minikube) DockerEnv(ctx context.Context) (runtime.DockerEndpoint, error) {
+	out, err := mc.output(ctx, "docker-env", "--shell=none", fmt.Sprintf("--profile=%s", mc.profile))
+	if err != nil {
+		return runtime.DockerEndpoint{}, fmt.Errorf("failed to retrieve docker env: %w", err)
+	}
+
+	return parseDockerEnv(out)
+}
+
+// DockerController returns a runtime.Docker bound to the daemon of the cluster. Images built with it are available to
+// the kubelet right away, so there is no need to load them. In multi-node clusters only the control plane node gets
+// the images, use BuildImage instead
+func (mc *Minikube) DockerController(ctx context.Context) (*runtime.Docker, error) {
+	endpoint, err := mc.DockerEnv(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.NewDockerControllerWithEndpoint(endpoint)
+}
+
+// BuildImage builds the image with minikube image build in all the nodes of the cluster, regardless of the container
+// runtime used. The build context is a copy of contextPath so the dockerfile is never written into it
+func (mc *Minikube) BuildImage(ctx context.Context, image, tag string, dockerfile []byte, contextPath string) error {
+	buildDir, err := os.MkdirTemp("", "minikube-testing-build-")
+	if err != nil {
+		return fmt.Errorf("error creating build directory: %w", err)
+	}
+	defer os.RemoveAll(buildDir)
+
+	if err = os.CopyFS(buildDir, os.DirFS(contextPath)); err != nil {
+		return fmt.Errorf("error copying build context %s: %w", contextPath, err)
+	}
+
+	if err = os.WriteFile(filepath.Join(buildDir, buildDockerfileName), dockerfile, 0o600); err != nil {
+		return fmt.Errorf("error writing dockerfile: %w", err)
+	}
+
+	err = mc.run(
+		ctx,
+		"image",
+		"build",
+		"--all",
+		fmt.Sprintf("--tag=%s:%s", image, tag),
+		fmt.Sprintf("--file=%s", buildDockerfileName),
+		fmt.Sprintf("--profile=%s", mc.profile),
+		buildDir,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to build image %s:%s: %w", image, tag, err)
+	}
+
+	return nil
+}
+
+// parseDockerEnv parses the KEY=VALUE lines printed by minikube docker-env --shell=none
+func parseDockerEnv(out []byte) (runtime.DockerEndpoint, error) {
+	endpoint := runtime.DockerEndpoint{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found {
+			continue
+		}
+
+		switch key {
+		case dockerEnvHost:
+			endpoint.Host = value
+		case dockerEnvCertPath:
+			endpoint.CertPath = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return runtime.DockerEndpoint{}, fmt.Errorf("error reading docker env: %w", err)
+	}
+
+	if endpoint.Host == "" {
+		return runtime.DockerEndpoint{}, fmt.Errorf("docker env does not contain %s", dockerEnvHost)
+	}
+
+	return endpoint, nil
+}
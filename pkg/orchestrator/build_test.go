@@ -0,0 +1,70 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yago-123/minikube-testing/pkg/runtime"
+)
+
+func TestDockerEnv(t *testing.T) {
+	out := "DOCKER_TLS_VERIFY=1\nDOCKER_HOST=tcp://192.168.49.2:2376\nDOCKER_CERT_PATH=/home/user/.minikube/certs\n" +
+		"MINIKUBE_ACTIVE_DOCKERD=my-profile\n"
+
+	replayer := NewReplayer([]CommandRecord{
+		{Name: "minikube", Args: []string{"docker-env", "--shell=none", "--profile=my-profile"}, Stdout: out},
+		{Name: "minikube", Args: []string{"docker-env", "--shell=none", "--profile=my-profile"}, ExitCode: 69},
+	})
+	mk := NewMinikubeWithProfile(io.Discard, io.Discard, "my-profile").WithRunner(replayer)
+
+	endpoint, err := mk.DockerEnv(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, runtime.DockerEndpoint{
+		Host:     "tcp://192.168.49.2:2376",
+		CertPath: "/home/user/.minikube/certs",
+	}, endpoint)
+
+	// the cluster uses a container runtime other than docker
+	_, err = mk.DockerEnv(context.Background())
+	require.Error(t, err)
+
+	_, err = parseDockerEnv([]byte("MINIKUBE_ACTIVE_DOCKERD=my-profile\n"))
+	require.Error(t, err)
+}
+
+func TestBuildImage(t *testing.T) {
+	contextPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(contextPath, "main.go"), []byte("package main\n"), 0o600))
+
+	var buildDir string
+	runner := runnerFunc(func(_ context.Context, cmd Command) error {
+		buildDir = cmd.Args[len(cmd.Args)-1]
+		require.Equal(t, []string{
+			"image", "build", "--all", "--tag=app:1.0", "--file=" + buildDockerfileName, "--profile=my-profile", buildDir,
+		}, cmd.Args)
+
+		require.FileExists(t, filepath.Join(buildDir, "main.go"))
+		dockerfile, err := os.ReadFile(filepath.Join(buildDir, buildDockerfileName))
+		require.NoError(t, err)
+		require.Equal(t, "FROM scratch\n", string(dockerfile))
+		return nil
+	})
+
+	mk := NewMinikubeWithProfile(io.Discard, io.Discard, "my-profile").WithRunner(runner)
+	require.NoError(t, mk.BuildImage(context.Background(), "app", "1.0", []byte("FROM scratch\n"), contextPath))
+
+	// the build context is removed and the original context is left untouched
+	require.NoDirExists(t, buildDir)
+	require.NoFileExists(t, filepath.Join(contextPath, buildDockerfileName))
+}
+
+// runnerFunc adapts a function to the CommandRunner interface
+type runnerFunc func(ctx context.Context, cmd Command) error
+
+func (f runnerFunc) Run(ctx context.Context, cmd Command) error {
+	return f(ctx, cmd)
+}
@@ -0,0 +1,64 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const (
+	// ProcessGracePeriod is the time given to the process to exit after being interrupted due to context
+	// cancellation, once it expires the process is killed
+	ProcessGracePeriod = 10 * time.Second
+)
+
+// Command represents the invocation of an external binary, env is appended to the environment of the current process
+type Command struct {
+	Name string
+	Args []string
+	Env  []string
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// CommandRunner executes the external binaries (minikube, kind, k3d...) on behalf of the orchestrators, so that their
+// logic can be tested without the binaries installed
+type CommandRunner interface {
+	Run(ctx context.Context, cmd Command) error
+}
+
+// make sure that ExecRunner implements the CommandRunner interface
+var _ CommandRunner = ExecRunner{}
+
+// ExecRunner is the default CommandRunner, executes the commands as child processes
+type ExecRunner struct{}
+
+// Run executes the command. If the context is cancelled the process is interrupted so that it can clean up, and killed
+// if it does not exit within ProcessGracePeriod
+func (ExecRunner) Run(ctx context.Context, command Command) error {
+	cmd := exec.CommandContext(ctx, command.Name, command.Args...)
+
+	cmd.Stdout = command.Stdout
+	cmd.Stderr = command.Stderr
+	if len(command.Env) > 0 {
+		cmd.Env = append(os.Environ(), command.Env...)
+	}
+
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = ProcessGracePeriod
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s cancelled: %w", command.Name, ctx.Err())
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecRunnerCancel(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep binary not available")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := ExecRunner{}.Run(ctx, Command{Name: "sleep", Args: []string{"60"}})
+
+	// sleep exits on interrupt, there is no need to wait for the grace period to kill it
+	require.Less(t, time.Since(start), ProcessGracePeriod)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,48 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/yago-123/minikube-testing/pkg/client"
+)
+
+// diagnosticsMinikubeLogs is the file of the diagnostics bundle that contains the output of minikube logs
+const diagnosticsMinikubeLogs = "minikube.log"
+
+// CollectDiagnostics writes a bundle into dir that can be uploaded as a CI artifact when a scenario fails: the
+// output of minikube logs together with the state collected by the client (nodes, events, pods, container logs and
+// helm releases) for the namespaces selected by the options, which default to the ones the client of the cluster
+// deployed into. Collection is best effort, everything that can be collected is written and the errors are returned
+// together
+func (mc *Minikube) CollectDiagnostics(ctx context.Context, dir string, opts client.DiagnosticsOptions) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("unable to create diagnostics directory: %w", err)
+	}
+
+	errs := []error{}
+
+	err := mc.run(
+		ctx,
+		"logs",
+		fmt.Sprintf("--file=%s", filepath.Join(dir, diagnosticsMinikubeLogs)),
+		fmt.Sprintf("--profile=%s", mc.profile),
+	)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to collect minikube logs: %w", err))
+	}
+
+	cli, err := mc.client()
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+
+	if err = cli.CollectDiagnostics(ctx, dir, opts); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
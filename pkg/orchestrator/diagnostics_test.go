@@ -0,0 +1,32 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yago-123/minikube-testing/pkg/client"
+)
+
+func TestCollectDiagnosticsMinikubeLogs(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "diagnostics")
+
+	replayer := NewReplayer([]CommandRecord{
+		{
+			Name:     "minikube",
+			Args:     []string{"logs", fmt.Sprintf("--file=%s", filepath.Join(dir, diagnosticsMinikubeLogs)), "--profile=missing-profile"},
+			ExitCode: 85,
+		},
+	})
+	mk := NewMinikubeWithProfile(io.Discard, io.Discard, "missing-profile").WithRunner(replayer)
+
+	// neither minikube logs nor the client can reach the cluster, both errors are reported
+	err := mk.CollectDiagnostics(context.Background(), dir, client.DiagnosticsOptions{})
+	require.ErrorContains(t, err, "failed to collect minikube logs")
+	require.ErrorContains(t, err, "unable to create client")
+	require.DirExists(t, dir)
+	require.Empty(t, replayer.Remaining())
+}
@@ -0,0 +1,185 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yago-123/minikube-testing/pkg/client"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+// make sure that ExistingCluster implements the Orchestrator interface
+var _ Orchestrator = (*ExistingCluster)(nil)
+
+// ErrImageLoadingNotSupported is returned when loading images into an existing cluster without a registry configured
+var ErrImageLoadingNotSupported = errors.New("existing clusters can't load images directly, configure a registry and a pusher")
+
+// ImagePusher tags and pushes local images, runtime.Docker implements it
+type ImagePusher interface {
+	TagImage(ctx context.Context, image, tag, targetImage, targetTag string) error
+	PushImage(ctx context.Context, image, tag string) error
+}
+
+// ExistingClusterOptions contains the settings used to run scenarios against an already existing cluster
+type ExistingClusterOptions struct {
+	// Namespaces are created during Create and deleted during Delete, namespaces that already existed are kept. The
+	// rest of the cluster is never modified
+	Namespaces []string
+	// Registry is the registry reachable from the cluster (e.g. localhost:5000) where images are pushed to
+	Registry string
+	// Pusher is used to tag and push images to the registry
+	Pusher ImagePusher
+}
+
+// ExistingCluster attaches to a cluster that already exists instead of creating a new one
+type ExistingCluster struct {
+	kubeconfig  string
+	kubeContext string
+	opts        ExistingClusterOptions
+
+	cli client.Client
+	// created contains the namespaces created by Create, the only ones removed by Delete
+	created []string
+}
+
+func NewExistingCluster(kubeconfigPath, kubeContext string, opts ExistingClusterOptions) *ExistingCluster {
+	return &ExistingCluster{
+		kubeconfig:  kubeconfigPath,
+		kubeContext: kubeContext,
+		opts:        opts,
+	}
+}
+
+// Create attaches to the cluster and verifies that it's reachable, that it runs the requested version (if any) and
+// that it has at least the number of nodes requested. cpusPerNode and memoryPerNode are ignored. The namespaces
+// configured are created in case they don't exist yet
+func (ec *ExistingCluster) Create(ctx context.Context, version string, nodes, _, _ uint) (client.Client, error) {
+	cli, err := client.NewClientWithKubeconfig(ec.kubeconfig, ec.kubeContext)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create client: %w", err)
+	}
+
+	serverVersion, err := fetchServerVersion(ctx, cli.ClientSet().Discovery().RESTClient())
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach cluster: %w", err)
+	}
+
+	if version != "" && !matchesVersion(serverVersion.GitVersion, version) {
+		return nil, fmt.Errorf("cluster runs version %s, expected %s", serverVersion.GitVersion, version)
+	}
+
+	nodeList, err := cli.ClientSet().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list nodes: %w", err)
+	}
+
+	if uint(len(nodeList.Items)) < nodes {
+		return nil, fmt.Errorf("cluster has %d nodes, expected at least %d", len(nodeList.Items), nodes)
+	}
+
+	// keep the client and the namespaces created so far, so that Delete can remove them even if Create fails
+	ec.cli = cli
+	ec.created, err = createNamespaces(ctx, cli.ClientSet().CoreV1().Namespaces(), ec.opts.Namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	return cli, nil
+}
+
+// LoadImage pushes the image to the configured registry, pods must reference the image as <registry>/<image>:<tag>
+func (ec *ExistingCluster) LoadImage(ctx context.Context, image, tag string) error {
+	if ec.opts.Registry == "" || ec.opts.Pusher == nil {
+		return ErrImageLoadingNotSupported
+	}
+
+	target := fmt.Sprintf("%s/%s", ec.opts.Registry, image)
+	if err := ec.opts.Pusher.TagImage(ctx, image, tag, target, tag); err != nil {
+		return fmt.Errorf("failed to load image %s: %w", fmt.Sprintf("%s:%s", image, tag), err)
+	}
+
+	if err := ec.opts.Pusher.PushImage(ctx, target, tag); err != nil {
+		return fmt.Errorf("failed to load image %s: %w", fmt.Sprintf("%s:%s", image, tag), err)
+	}
+
+	return nil
+}
+
+// Delete removes the namespaces created by Create, namespaces that already existed and the cluster itself are left
+// untouched
+func (ec *ExistingCluster) Delete(ctx context.Context) error {
+	if ec.cli == nil {
+		return nil
+	}
+
+	if err := deleteNamespaces(ctx, ec.cli.ClientSet().CoreV1().Namespaces(), ec.created); err != nil {
+		return err
+	}
+	ec.created = []string{}
+
+	return nil
+}
+
+// createNamespaces creates the namespaces that don't exist yet, returns the ones created (also when it fails)
+func createNamespaces(ctx context.Context, nsClient corev1.NamespaceInterface, namespaces []string) ([]string, error) {
+	created := []string{}
+	for _, ns := range namespaces {
+		_, err := nsClient.Create(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}}, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			continue
+		}
+		if err != nil {
+			return created, fmt.Errorf("unable to create namespace %s: %w", ns, err)
+		}
+		created = append(created, ns)
+	}
+
+	return created, nil
+}
+
+// deleteNamespaces deletes the namespaces, the ones that are already gone are ignored
+func deleteNamespaces(ctx context.Context, nsClient corev1.NamespaceInterface, namespaces []string) error {
+	for _, ns := range namespaces {
+		err := nsClient.Delete(ctx, ns, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete namespace %s: %w", ns, err)
+		}
+	}
+
+	return nil
+}
+
+// fetchServerVersion retrieves the version of the API server, unlike the discovery client it stops as soon as the
+// context finishes
+func fetchServerVersion(ctx context.Context, restClient rest.Interface) (version.Info, error) {
+	content, err := restClient.Get().AbsPath("/version").DoRaw(ctx)
+	if err != nil {
+		return version.Info{}, fmt.Errorf("error retrieving server version: %w", err)
+	}
+
+	var info version.Info
+	if err = json.Unmarshal(content, &info); err != nil {
+		return version.Info{}, fmt.Errorf("error parsing server version: %w", err)
+	}
+
+	return info, nil
+}
+
+// matchesVersion checks whether the server version (e.g. v1.30.2+k3s1) matches the requested version, which can be
+// partial (e.g. 1.30)
+func matchesVersion(serverVersion, version string) bool {
+	serverVersion = strings.TrimPrefix(serverVersion, "v")
+	version = strings.TrimPrefix(version, "v")
+
+	return serverVersion == version || strings.HasPrefix(serverVersion, version+".") ||
+		strings.HasPrefix(serverVersion, version+"+") || strings.HasPrefix(serverVersion, version+"-")
+}
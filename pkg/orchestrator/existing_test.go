@@ -0,0 +1,94 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+func TestMatchesVersion(t *testing.T) {
+	tests := []struct {
+		serverVersion string
+		version       string
+		want          bool
+	}{
+		{serverVersion: "v1.30.2", version: "1.30.2", want: true},
+		{serverVersion: "v1.30.2", version: "1.30", want: true},
+		{serverVersion: "v1.30.2+k3s1", version: "v1.30.2", want: true},
+		{serverVersion: "v1.30.2", version: "1.3", want: false},
+		{serverVersion: "v1.31.0", version: "1.30", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.serverVersion+"_"+tt.version, func(t *testing.T) {
+			require.Equal(t, tt.want, matchesVersion(tt.serverVersion, tt.version))
+		})
+	}
+}
+
+func TestExistingClusterLoadImageWithoutRegistry(t *testing.T) {
+	ec := NewExistingCluster("", "", ExistingClusterOptions{})
+	require.ErrorIs(t, ec.LoadImage(context.Background(), "my-image", "latest"), ErrImageLoadingNotSupported)
+	require.NoError(t, ec.Delete(context.Background()))
+}
+
+func TestExistingClusterNamespaces(t *testing.T) {
+	ctx := context.Background()
+	nsClient := fake.NewSimpleClientset(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "existing"}}).CoreV1().Namespaces()
+
+	created, err := createNamespaces(ctx, nsClient, []string{"existing", "test"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"test"}, created)
+
+	require.NoError(t, deleteNamespaces(ctx, nsClient, created))
+	// deleting a namespace that is already gone is not an error
+	require.NoError(t, deleteNamespaces(ctx, nsClient, created))
+
+	// the namespace that already existed survives
+	nsList, err := nsClient.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, nsList.Items, 1)
+	require.Equal(t, "existing", nsList.Items[0].Name)
+}
+
+func TestFetchServerVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"major": "1", "minor": "30", "gitVersion": "v1.30.2+k3s1"}`))
+	}))
+	defer server.Close()
+
+	cs, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	require.NoError(t, err)
+
+	info, err := fetchServerVersion(context.Background(), cs.Discovery().RESTClient())
+	require.NoError(t, err)
+	require.Equal(t, "v1.30.2+k3s1", info.GitVersion)
+
+	// an unresponsive cluster must not block once the context finishes
+	unblock := make(chan struct{})
+	unresponsive := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		<-unblock
+	}))
+	defer unresponsive.Close()
+	defer close(unblock)
+
+	cs, err = kubernetes.NewForConfig(&rest.Config{Host: unresponsive.URL})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = fetchServerVersion(ctx, cs.Discovery().RESTClient())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
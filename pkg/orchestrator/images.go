@@ -0,0 +1,293 @@
+package orchestrator
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dockerBinary = "docker"
+
+	// ImageLoadConcurrency is the maximum number of images transferred to the cluster at the same time
+	ImageLoadConcurrency = 4
+
+	defaultRegistry  = "docker.io"
+	defaultNamespace = "library"
+	defaultTag       = "latest"
+	digestPrefix     = "sha256:"
+	archiveManifest  = "manifest.json"
+)
+
+type ImageLoadStatus string
+
+const (
+	ImageLoaded  ImageLoadStatus = "loaded"
+	ImageSkipped ImageLoadStatus = "skipped"
+	ImageFailed  ImageLoadStatus = "failed"
+)
+
+// ImageLoadResult contains the outcome of loading one of the images requested in LoadImages
+type ImageLoadResult struct {
+	// Ref is the image reference or the tarball path as requested
+	Ref    string
+	Status ImageLoadStatus
+	// Duration includes the presence check and the transfer of the image
+	Duration time.Duration
+	Err      error
+}
+
+// clusterImage represents an image of the cluster runtime as reported by minikube image ls
+type clusterImage struct {
+	ID       string   `json:"id"`
+	RepoTags []string `json:"repoTags"`
+}
+
+// imageIdentity identifies an image by the ID of its config and the tags it's known by
+type imageIdentity struct {
+	ID   string
+	Tags []string
+}
+
+// archiveManifestEntry represents an image inside the manifest.json of a docker save tarball
+type archiveManifestEntry struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+}
+
+// LoadImages loads the images into the cluster runtime in parallel. Each ref is either an image of the local docker
+// daemon (image:tag) or the path to a tarball generated by docker save. Images already present in the cluster with the
+// same ID and tag are skipped. A result is returned for every ref, together with the errors of the ones that failed
+func (mc *Minikube) LoadImages(ctx context.Context, refs ...string) ([]ImageLoadResult, error) {
+	present, err := mc.clusterImages(ctx)
+	if err != nil {
+		return []ImageLoadResult{}, err
+	}
+
+	results := make([]ImageLoadResult, len(refs))
+	sem := make(chan struct{}, ImageLoadConcurrency)
+	wg := sync.WaitGroup{}
+
+	for i, ref := range refs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = mc.loadImageIfMissing(ctx, ref, present)
+		}()
+	}
+	wg.Wait()
+
+	errs := []error{}
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// loadImageIfMissing transfers the image unless all the images that it contains are already in the cluster
+func (mc *Minikube) loadImageIfMissing(ctx context.Context, ref string, present []clusterImage) ImageLoadResult {
+	start := time.Now()
+	result := ImageLoadResult{Ref: ref}
+
+	identities, err := mc.imageIdentities(ctx, ref)
+	if err == nil && imagesPresent(identities, present) {
+		result.Status = ImageSkipped
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	if err == nil {
+		err = mc.loadImage(ctx, ref)
+	}
+
+	result.Status = ImageLoaded
+	if err != nil {
+		result.Status = ImageFailed
+		result.Err = err
+	}
+	result.Duration = time.Since(start)
+
+	return result
+}
+
+// loadImage transfers the image or the tarball to the cluster runtime. Host images are recorded before being
+// transferred, see Reap
+func (mc *Minikube) loadImage(ctx context.Context, ref string) error {
+	if !isTarball(ref) {
+		if err := recordImage(mc.profile, ref); err != nil {
+			return err
+		}
+	}
+
+	if err := mc.run(ctx, "image", "load", "--profile", mc.profile, ref); err != nil {
+		return fmt.Errorf("failed to load image %s: %w", ref, err)
+	}
+
+	return nil
+}
+
+// clusterImages lists the images present in the container runtime of the cluster
+func (mc *Minikube) clusterImages(ctx context.Context) ([]clusterImage, error) {
+	out, err := mc.output(ctx, "image", "ls", "--format=json", fmt.Sprintf("--profile=%s", mc.profile))
+	if err != nil {
+		return []clusterImage{}, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	images := []clusterImage{}
+	if err = json.Unmarshal(out, &images); err != nil {
+		return []clusterImage{}, fmt.Errorf("error parsing images: %w", err)
+	}
+
+	return images, nil
+}
+
+// imageIdentities returns the identity of the images contained in the ref, refs pointing to an existing file are
+// treated as tarballs and the rest as images of the local docker daemon
+func (mc *Minikube) imageIdentities(ctx context.Context, ref string) ([]imageIdentity, error) {
+	if isTarball(ref) {
+		return readTarballIdentities(ref)
+	}
+
+	out := new(bytes.Buffer)
+	err := mc.runner.Run(ctx, Command{
+		Name:   dockerBinary,
+		Args:   []string{"image", "inspect", "--format={{.Id}}", ref},
+		Stdout: out,
+		Stderr: mc.stderr,
+	})
+	if err != nil {
+		return []imageIdentity{}, fmt.Errorf("failed to inspect image %s: %w", ref, err)
+	}
+
+	return []imageIdentity{{ID: strings.TrimSpace(out.String()), Tags: []string{ref}}}, nil
+}
+
+// isTarball checks whether the ref points to an existing file instead of an image of the local docker daemon
+func isTarball(ref string) bool {
+	info, err := os.Stat(ref)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// readTarballIdentities reads the images contained in a docker save tarball from its manifest
+func readTarballIdentities(tarball string) ([]imageIdentity, error) {
+	f, err := os.Open(tarball)
+	if err != nil {
+		return []imageIdentity{}, fmt.Errorf("error opening tarball %s: %w", tarball, err)
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		header, errNext := tr.Next()
+		if errors.Is(errNext, io.EOF) {
+			return []imageIdentity{}, fmt.Errorf("tarball %s does not contain %s", tarball, archiveManifest)
+		}
+		if errNext != nil {
+			return []imageIdentity{}, fmt.Errorf("error reading tarball %s: %w", tarball, errNext)
+		}
+
+		if path.Clean(header.Name) != archiveManifest {
+			continue
+		}
+
+		entries := []archiveManifestEntry{}
+		if err = json.NewDecoder(tr).Decode(&entries); err != nil {
+			return []imageIdentity{}, fmt.Errorf("error parsing manifest of tarball %s: %w", tarball, err)
+		}
+
+		identities := []imageIdentity{}
+		for _, entry := range entries {
+			// the config is named after its digest, either <hex>.json or blobs/sha256/<hex>
+			id := strings.TrimSuffix(path.Base(entry.Config), ".json")
+			identities = append(identities, imageIdentity{ID: id, Tags: entry.RepoTags})
+		}
+
+		return identities, nil
+	}
+}
+
+// imagesPresent checks whether all the images are in the cluster with the same ID and all their tags
+func imagesPresent(identities []imageIdentity, present []clusterImage) bool {
+	if len(identities) == 0 {
+		return false
+	}
+
+	for _, identity := range identities {
+		if len(identity.Tags) == 0 || !imagePresent(identity, present) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func imagePresent(identity imageIdentity, present []clusterImage) bool {
+	for _, image := range present {
+		if normalizeImageID(image.ID) != normalizeImageID(identity.ID) {
+			continue
+		}
+
+		tags := map[string]bool{}
+		for _, tag := range image.RepoTags {
+			tags[normalizeImageRef(tag)] = true
+		}
+
+		for _, tag := range identity.Tags {
+			if !tags[normalizeImageRef(tag)] {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// normalizeImageID removes the digest algorithm, runtimes differ on whether they report it
+func normalizeImageID(id string) string {
+	return strings.TrimPrefix(id, digestPrefix)
+}
+
+// normalizeImageRef expands the short image references to the fully qualified form used by the container runtimes
+// (e.g. nginx becomes docker.io/library/nginx:latest)
+func normalizeImageRef(ref string) string {
+	name, tag := splitImageRef(ref)
+
+	domain, rest, found := strings.Cut(name, "/")
+	if !found || (!strings.ContainsAny(domain, ".:") && domain != "localhost") {
+		domain, rest = defaultRegistry, name
+	}
+
+	if domain == defaultRegistry && !strings.Contains(rest, "/") {
+		rest = fmt.Sprintf("%s/%s", defaultNamespace, rest)
+	}
+
+	return fmt.Sprintf("%s/%s:%s", domain, rest, tag)
+}
+
+// splitImageRef splits the reference into the image name and the tag, which defaults to latest
+func splitImageRef(ref string) (string, string) {
+	// the tag separator must come after the last slash, otherwise it's the port of the registry
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i], ref[i+1:]
+	}
+
+	return ref, defaultTag
+}
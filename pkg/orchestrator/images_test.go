@@ -0,0 +1,83 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeImageRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{ref: "nginx", want: "docker.io/library/nginx:latest"},
+		{ref: "nginx:1.27", want: "docker.io/library/nginx:1.27"},
+		{ref: "yagoninja/api-server-test:0.1.0", want: "docker.io/yagoninja/api-server-test:0.1.0"},
+		{ref: "docker.io/library/nginx:1.27", want: "docker.io/library/nginx:1.27"},
+		{ref: "localhost:5000/app", want: "localhost:5000/app:latest"},
+		{ref: "registry.k8s.io/pause:3.10", want: "registry.k8s.io/pause:3.10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			require.Equal(t, tt.want, normalizeImageRef(tt.ref))
+		})
+	}
+}
+
+func TestLoadImages(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	tarball := filepath.Join(t.TempDir(), "app.tar")
+	generateImageTarball(t, tarball, `[{"Config":"blobs/sha256/cccc","RepoTags":["app:1.0"],"Layers":[]}]`)
+
+	imageLs := `[
+		{"id":"sha256:aaaa","repoDigests":[],"repoTags":["docker.io/library/nginx:1.27"],"size":"1000"},
+		{"id":"cccc","repoDigests":[],"repoTags":["docker.io/library/app:1.0"],"size":"1000"}
+	]`
+
+	replayer := NewReplayer([]CommandRecord{
+		{Name: "minikube", Args: []string{"image", "ls", "--format=json", "--profile=my-profile"}, Stdout: imageLs},
+		{Name: "docker", Args: []string{"image", "inspect", "--format={{.Id}}", "nginx:1.27"}, Stdout: "sha256:aaaa\n"},
+		{Name: "docker", Args: []string{"image", "inspect", "--format={{.Id}}", "redis:7"}, Stdout: "sha256:bbbb\n"},
+		{Name: "minikube", Args: []string{"image", "load", "--profile", "my-profile", "redis:7"}},
+		{Name: "docker", Args: []string{"image", "inspect", "--format={{.Id}}", "missing:1"}, ExitCode: 1},
+	})
+	mk := NewMinikubeWithProfile(io.Discard, io.Discard, "my-profile").WithRunner(replayer)
+
+	results, err := mk.LoadImages(context.Background(), "nginx:1.27", "redis:7", tarball, "missing:1")
+	require.Error(t, err)
+	require.Len(t, results, 4)
+
+	require.Equal(t, ImageSkipped, results[0].Status)
+	require.Equal(t, ImageLoaded, results[1].Status)
+	require.Equal(t, ImageSkipped, results[2].Status)
+	require.Equal(t, ImageFailed, results[3].Status)
+	require.Error(t, results[3].Err)
+	require.Empty(t, replayer.Remaining())
+
+	// only the images of the host that were transferred are recorded for the reaper
+	images, err := recordedImages("my-profile")
+	require.NoError(t, err)
+	require.Equal(t, []string{"redis:7"}, images)
+}
+
+func generateImageTarball(t *testing.T, path, manifest string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: archiveManifest, Mode: 0o644, Size: int64(len(manifest))}))
+	_, err = tw.Write([]byte(manifest))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+}
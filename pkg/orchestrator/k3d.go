@@ -0,0 +1,198 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yago-123/minikube-testing/pkg/client"
+)
+
+const (
+	k3dBinary = "k3d"
+
+	// K3sImage is the image used for the k3d nodes, the Kubernetes version is appended as tag
+	K3sImage = "rancher/k3s"
+	// K3sImageSuffix is the suffix of the k3s release appended to the Kubernetes version
+	K3sImageSuffix = "k3s1"
+)
+
+// make sure that K3d implements the Orchestrator interface
+var _ Orchestrator = (*K3d)(nil)
+
+// K3dOptions contains the k3s specific settings of the cluster
+type K3dOptions struct {
+	// Servers is the number of control plane nodes, the rest of the nodes requested are created as agents.
+	// Defaults to 1
+	Servers uint
+	// DisableTraefik skips the installation of the traefik ingress controller bundled with k3s
+	DisableTraefik bool
+}
+
+type K3d struct {
+	stdout io.Writer
+	stderr io.Writer
+	name   string
+	opts   K3dOptions
+
+	runner    CommandRunner
+	readiness client.Readiness
+}
+
+// NewK3d creates the orchestrator for a new cluster with a generated name, see Reap for cleaning up the clusters
+// leaked by processes that died before deleting them
+func NewK3d(stdout, stderr io.Writer) *K3d {
+	return NewK3dWithOptions(stdout, stderr, generateName(K3dNamePrefix), K3dOptions{})
+}
+
+func NewK3dWithOptions(stdout, stderr io.Writer, name string, opts K3dOptions) *K3d {
+	return &K3d{
+		stdout: stdout,
+		stderr: stderr,
+		name:   name,
+		opts:   opts,
+
+		runner:    ExecRunner{},
+		readiness: client.DefaultReadiness(),
+	}
+}
+
+// WithReadiness replaces the readiness definition that Create waits for once the cluster has started
+func (k *K3d) WithReadiness(readiness client.Readiness) *K3d {
+	k.readiness = readiness
+	return k
+}
+
+// WithRunner replaces the runner used to execute k3d, see Recorder and Replayer for running without the binary
+func (k *K3d) WithRunner(runner CommandRunner) *K3d {
+	k.runner = runner
+	return k
+}
+
+// Create starts a k3d cluster with the configured number of servers and nodes - servers agents and blocks until it
+// meets the readiness definition or the context finishes. k3d can't limit the CPUs of the nodes, so cpusPerNode is
+// ignored. The credentials are written into a kubeconfig file scoped to the cluster, the developer kubeconfig is never
+// modified
+func (k *K3d) Create(ctx context.Context, version string, nodes, _, memoryPerNode uint) (client.Client, error) {
+	args, err := k3dCreateArgs(k.name, version, nodes, memoryPerNode, k.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = k.run(ctx, args...); err != nil {
+		return nil, fmt.Errorf("failed to start k3d: %w", err)
+	}
+
+	if err = os.MkdirAll(kubeconfigDir(), 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create kubeconfig directory: %w", err)
+	}
+
+	err = k.run(
+		ctx,
+		"kubeconfig",
+		"write",
+		k.name,
+		fmt.Sprintf("--output=%s", k.Kubeconfig()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+
+	cli, err := client.NewClientWithKubeconfig(k.Kubeconfig(), "")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create client: %w", err)
+	}
+
+	if err = cli.WaitForReadiness(ctx, k.readiness); err != nil {
+		return nil, fmt.Errorf("cluster not ready: %w", err)
+	}
+
+	return cli, nil
+}
+
+// LoadImage imports the image of the local docker daemon into the nodes, the image is recorded first, see Reap
+func (k *K3d) LoadImage(ctx context.Context, image, tag string) error {
+	if err := recordImage(k.name, fmt.Sprintf("%s:%s", image, tag)); err != nil {
+		return err
+	}
+
+	err := k.run(
+		ctx,
+		"image",
+		"import",
+		fmt.Sprintf("--cluster=%s", k.name),
+		fmt.Sprintf("%s:%s", image, tag),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to load image %s: %w", fmt.Sprintf("%s:%s", image, tag), err)
+	}
+
+	return nil
+}
+
+func (k *K3d) Delete(ctx context.Context) error {
+	err := k.run(
+		ctx,
+		"cluster",
+		"delete",
+		k.name,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete k3d: %w", err)
+	}
+
+	return removeClusterFiles(k.Kubeconfig(), clusterFilePath(k.name, imagesRecordName))
+}
+
+// Kubeconfig returns the path of the kubeconfig file that contains the credentials of the cluster
+func (k *K3d) Kubeconfig() string {
+	return clusterFilePath(k.name, "kubeconfig")
+}
+
+// run executes k3d with the given arguments
+func (k *K3d) run(ctx context.Context, args ...string) error {
+	return k.runner.Run(ctx, Command{
+		Name:   k3dBinary,
+		Args:   args,
+		Stdout: k.stdout,
+		Stderr: k.stderr,
+	})
+}
+
+// k3dCreateArgs generates the arguments of the k3d cluster create command
+func k3dCreateArgs(name, version string, nodes, memoryPerNode uint, opts K3dOptions) ([]string, error) {
+	servers := opts.Servers
+	if servers == 0 {
+		servers = 1
+	}
+
+	if nodes < servers {
+		return []string{}, fmt.Errorf("number of nodes (%d) must be at least the number of servers (%d)", nodes, servers)
+	}
+
+	args := []string{
+		"cluster",
+		"create",
+		name,
+		fmt.Sprintf("--image=%s:v%s-%s", K3sImage, version, K3sImageSuffix),
+		fmt.Sprintf("--servers=%d", servers),
+		fmt.Sprintf("--agents=%d", nodes-servers),
+		"--kubeconfig-update-default=false",
+		"--kubeconfig-switch-context=false",
+		"--wait",
+	}
+
+	if memoryPerNode > 0 {
+		args = append(args,
+			fmt.Sprintf("--servers-memory=%dm", memoryPerNode),
+			fmt.Sprintf("--agents-memory=%dm", memoryPerNode),
+		)
+	}
+
+	if opts.DisableTraefik {
+		args = append(args, "--k3s-arg=--disable=traefik@server:*")
+	}
+
+	return args, nil
+}
@@ -0,0 +1,34 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestK3dCreateArgs(t *testing.T) {
+	args, err := k3dCreateArgs("my-cluster", "1.30.0", 3, 2048, K3dOptions{DisableTraefik: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"cluster",
+		"create",
+		"my-cluster",
+		"--image=rancher/k3s:v1.30.0-k3s1",
+		"--servers=1",
+		"--agents=2",
+		"--kubeconfig-update-default=false",
+		"--kubeconfig-switch-context=false",
+		"--wait",
+		"--servers-memory=2048m",
+		"--agents-memory=2048m",
+		"--k3s-arg=--disable=traefik@server:*",
+	}, args)
+
+	args, err = k3dCreateArgs("my-cluster", "1.30.0", 3, 0, K3dOptions{Servers: 3})
+	require.NoError(t, err)
+	require.Contains(t, args, "--servers=3")
+	require.Contains(t, args, "--agents=0")
+
+	_, err = k3dCreateArgs("my-cluster", "1.30.0", 1, 0, K3dOptions{Servers: 3})
+	require.Error(t, err)
+}
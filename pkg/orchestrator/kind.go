@@ -0,0 +1,204 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yago-123/minikube-testing/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	kindBinary = "kind"
+
+	// KindNodeImage is the image used for the kind nodes, the Kubernetes version is appended as tag
+	KindNodeImage = "kindest/node"
+
+	kindConfigKind       = "Cluster"
+	kindConfigAPIVersion = "kind.x-k8s.io/v1alpha4"
+	kindRoleControlPlane = "control-plane"
+	kindRoleWorker       = "worker"
+)
+
+// make sure that Kind implements the Orchestrator interface
+var _ Orchestrator = (*Kind)(nil)
+
+// kindConfig represents the kind cluster configuration file
+type kindConfig struct {
+	Kind       string     `json:"kind"`
+	APIVersion string     `json:"apiVersion"`
+	Nodes      []kindNode `json:"nodes"`
+}
+
+type kindNode struct {
+	Role  string `json:"role"`
+	Image string `json:"image,omitempty"`
+}
+
+type Kind struct {
+	stdout io.Writer
+	stderr io.Writer
+	name   string
+
+	runner    CommandRunner
+	readiness client.Readiness
+}
+
+// NewKind creates the orchestrator for a new cluster with a generated name, see Reap for cleaning up the clusters
+// leaked by processes that died before deleting them
+func NewKind(stdout, stderr io.Writer) *Kind {
+	return &Kind{
+		stdout: stdout,
+		stderr: stderr,
+		name:   generateName(ProfilePrefix),
+
+		runner:    ExecRunner{},
+		readiness: client.DefaultReadiness(),
+	}
+}
+
+func NewKindWithName(stdout, stderr io.Writer, name string) *Kind {
+	return &Kind{
+		stdout: stdout,
+		stderr: stderr,
+		name:   name,
+
+		runner:    ExecRunner{},
+		readiness: client.DefaultReadiness(),
+	}
+}
+
+// WithReadiness replaces the readiness definition that Create waits for once the cluster has started
+func (k *Kind) WithReadiness(readiness client.Readiness) *Kind {
+	k.readiness = readiness
+	return k
+}
+
+// WithRunner replaces the runner used to execute kind, see Recorder and Replayer for running without the binary
+func (k *Kind) WithRunner(runner CommandRunner) *Kind {
+	k.runner = runner
+	return k
+}
+
+// Create starts a kind cluster with one control plane and nodes - 1 workers and blocks until it meets the readiness
+// definition or the context finishes. Kind nodes are containers that share the resources of the host, so cpusPerNode
+// and memoryPerNode are ignored. The credentials are written into a kubeconfig file scoped to the cluster, the
+// developer kubeconfig is never modified
+func (k *Kind) Create(ctx context.Context, version string, nodes, _, _ uint) (client.Client, error) {
+	config, err := generateKindConfig(version, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(kubeconfigDir(), 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create kubeconfig directory: %w", err)
+	}
+
+	configPath := clusterFilePath(k.name, "kind.yaml")
+	if err = os.WriteFile(configPath, config, 0o600); err != nil {
+		return nil, fmt.Errorf("unable to write kind config: %w", err)
+	}
+	defer os.Remove(configPath)
+
+	err = k.run(
+		ctx,
+		"create",
+		"cluster",
+		fmt.Sprintf("--name=%s", k.name),
+		fmt.Sprintf("--config=%s", configPath),
+		fmt.Sprintf("--kubeconfig=%s", k.Kubeconfig()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start kind: %w", err)
+	}
+
+	cli, err := client.NewClientWithKubeconfig(k.Kubeconfig(), "")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create client: %w", err)
+	}
+
+	if err = cli.WaitForReadiness(ctx, k.readiness); err != nil {
+		return nil, fmt.Errorf("cluster not ready: %w", err)
+	}
+
+	return cli, nil
+}
+
+// LoadImage transfers the image of the local docker daemon to the nodes, the image is recorded first, see Reap
+func (k *Kind) LoadImage(ctx context.Context, image, tag string) error {
+	if err := recordImage(k.name, fmt.Sprintf("%s:%s", image, tag)); err != nil {
+		return err
+	}
+
+	err := k.run(
+		ctx,
+		"load",
+		"docker-image",
+		fmt.Sprintf("--name=%s", k.name),
+		fmt.Sprintf("%s:%s", image, tag),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to load image %s: %w", fmt.Sprintf("%s:%s", image, tag), err)
+	}
+
+	return nil
+}
+
+func (k *Kind) Delete(ctx context.Context) error {
+	err := k.run(
+		ctx,
+		"delete",
+		"cluster",
+		fmt.Sprintf("--name=%s", k.name),
+		fmt.Sprintf("--kubeconfig=%s", k.Kubeconfig()),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete kind: %w", err)
+	}
+
+	return removeClusterFiles(k.Kubeconfig(), clusterFilePath(k.name, imagesRecordName))
+}
+
+// Kubeconfig returns the path of the kubeconfig file that contains the credentials of the cluster
+func (k *Kind) Kubeconfig() string {
+	return clusterFilePath(k.name, "kubeconfig")
+}
+
+// run executes kind with the given arguments
+func (k *Kind) run(ctx context.Context, args ...string) error {
+	return k.runner.Run(ctx, Command{
+		Name:   kindBinary,
+		Args:   args,
+		Stdout: k.stdout,
+		Stderr: k.stderr,
+	})
+}
+
+// generateKindConfig generates the kind configuration for a cluster with a single control plane and nodes - 1
+// workers running the given Kubernetes version
+func generateKindConfig(version string, nodes uint) ([]byte, error) {
+	if nodes == 0 {
+		return nil, fmt.Errorf("at least one node is required")
+	}
+
+	image := fmt.Sprintf("%s:v%s", KindNodeImage, version)
+
+	config := kindConfig{
+		Kind:       kindConfigKind,
+		APIVersion: kindConfigAPIVersion,
+		Nodes:      []kindNode{{Role: kindRoleControlPlane, Image: image}},
+	}
+
+	for range nodes - 1 {
+		config.Nodes = append(config.Nodes, kindNode{Role: kindRoleWorker, Image: image})
+	}
+
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate kind config: %w", err)
+	}
+
+	return content, nil
+}
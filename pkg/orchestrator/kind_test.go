@@ -0,0 +1,41 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateKindConfig(t *testing.T) {
+	config, err := generateKindConfig("1.30.0", 3)
+	require.NoError(t, err)
+
+	expected := `apiVersion: kind.x-k8s.io/v1alpha4
+kind: Cluster
+nodes:
+- image: kindest/node:v1.30.0
+  role: control-plane
+- image: kindest/node:v1.30.0
+  role: worker
+- image: kindest/node:v1.30.0
+  role: worker
+`
+	require.Equal(t, expected, string(config))
+
+	_, err = generateKindConfig("1.30.0", 0)
+	require.Error(t, err)
+}
+
+func TestNewOrchestrator(t *testing.T) {
+	orch, err := NewOrchestrator(BackendKind, io.Discard, io.Discard)
+	require.NoError(t, err)
+	require.IsType(t, &Kind{}, orch)
+
+	orch, err = NewOrchestrator(BackendMinikube, io.Discard, io.Discard)
+	require.NoError(t, err)
+	require.IsType(t, &Minikube{}, orch)
+
+	_, err = NewOrchestrator("unknown", io.Discard, io.Discard)
+	require.Error(t, err)
+}
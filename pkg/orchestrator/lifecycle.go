@@ -0,0 +1,89 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yago-123/minikube-testing/pkg/client"
+)
+
+// Stop shuts down the nodes of the cluster while keeping its state, so that it can be started again with Start
+// without going through the whole provisioning
+func (mc *Minikube) Stop(ctx context.Context) error {
+	if err := mc.run(ctx, "stop", fmt.Sprintf("--profile=%s", mc.profile)); err != nil {
+		return fmt.Errorf("failed to stop minikube: %w", err)
+	}
+
+	return nil
+}
+
+// Start restarts a cluster that was stopped and blocks until it meets the readiness definition or the context
+// finishes. minikube may publish the API server in a different port after the restart, so the client returned must
+// replace any client obtained before stopping the cluster
+func (mc *Minikube) Start(ctx context.Context) (client.Client, error) {
+	// the profile keeps the settings used during creation, there is no need to pass the spec again
+	if err := mc.run(ctx, "start", fmt.Sprintf("--profile=%s", mc.profile)); err != nil {
+		return nil, fmt.Errorf("failed to start minikube: %w", err)
+	}
+
+	return mc.waitForRestart(ctx)
+}
+
+// Pause freezes the control plane and the workloads of the given namespaces (all of them if empty) without
+// stopping the nodes
+func (mc *Minikube) Pause(ctx context.Context, namespaces ...string) error {
+	if err := mc.run(ctx, mc.pauseArgs("pause", namespaces)...); err != nil {
+		return fmt.Errorf("failed to pause minikube: %w", err)
+	}
+
+	return nil
+}
+
+// Unpause resumes the containers frozen by Pause and blocks until the cluster meets the readiness definition again
+func (mc *Minikube) Unpause(ctx context.Context, namespaces ...string) (client.Client, error) {
+	if err := mc.run(ctx, mc.pauseArgs("unpause", namespaces)...); err != nil {
+		return nil, fmt.Errorf("failed to unpause minikube: %w", err)
+	}
+
+	return mc.waitForRestart(ctx)
+}
+
+// pauseArgs returns the arguments of minikube pause and unpause for the given namespaces
+func (mc *Minikube) pauseArgs(command string, namespaces []string) []string {
+	args := []string{command, fmt.Sprintf("--profile=%s", mc.profile)}
+	if len(namespaces) == 0 {
+		return append(args, "--all-namespaces")
+	}
+
+	for _, ns := range namespaces {
+		args = append(args, fmt.Sprintf("--namespaces=%s", ns))
+	}
+
+	return args
+}
+
+// waitForRestart rebuilds the client from the kubeconfig updated by minikube and waits until the cluster is ready
+func (mc *Minikube) waitForRestart(ctx context.Context) (client.Client, error) {
+	mc.cli = nil
+
+	if err := mc.waitForReadiness(ctx); err != nil {
+		return nil, err
+	}
+
+	return mc.client()
+}
+
+// waitForReadiness blocks until the cluster meets the readiness definition or the context finishes, the nodes stopped
+// with StopNode are not required to be ready
+func (mc *Minikube) waitForReadiness(ctx context.Context) error {
+	cli, err := mc.client()
+	if err != nil {
+		return err
+	}
+
+	if err = cli.WaitForReadiness(ctx, mc.nodeReadiness()); err != nil {
+		return fmt.Errorf("cluster not ready: %w", err)
+	}
+
+	return nil
+}
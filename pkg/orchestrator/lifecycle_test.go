@@ -0,0 +1,17 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseArgs(t *testing.T) {
+	mk := NewMinikubeWithProfile(io.Discard, io.Discard, "my-profile")
+
+	require.Equal(t, []string{"pause", "--profile=my-profile", "--all-namespaces"}, mk.pauseArgs("pause", nil))
+	require.Equal(t, []string{
+		"unpause", "--profile=my-profile", "--namespaces=default", "--namespaces=apps",
+	}, mk.pauseArgs("unpause", []string{"default", "apps"}))
+}
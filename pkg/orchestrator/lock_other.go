@@ -0,0 +1,18 @@
+//go:build !linux && !darwin
+
+package orchestrator
+
+import (
+	"errors"
+	"os"
+)
+
+var errLockNotSupported = errors.New("file locks are not supported in this platform")
+
+func tryLockFile(_ string) (*os.File, bool, error) {
+	return nil, false, errLockNotSupported
+}
+
+func unlockFile(_ *os.File) error {
+	return errLockNotSupported
+}
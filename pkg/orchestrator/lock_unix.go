@@ -0,0 +1,62 @@
+//go:build linux || darwin
+
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// tryLockFile acquires an exclusive lock over the file without blocking, returns false if another process holds it.
+// The lock is released by the kernel if the process dies, so leases never leak across crashes. Lock files can be
+// removed by their holder (e.g. when a cluster is evicted), a lock acquired over a file that is no longer in the path
+// is stale and the file is opened again
+func tryLockFile(path string) (*os.File, bool, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+		if err != nil {
+			return nil, false, fmt.Errorf("error opening lock %s: %w", path, err)
+		}
+
+		if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			f.Close()
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				return nil, false, nil
+			}
+			return nil, false, fmt.Errorf("error acquiring lock %s: %w", path, err)
+		}
+
+		if sameFile(f, path) {
+			return f, true, nil
+		}
+		_ = unlockFile(f)
+	}
+}
+
+// sameFile checks whether the open file is still the one present in the path
+func sameFile(f *os.File, path string) bool {
+	opened, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(opened, current)
+}
+
+// unlockFile releases the lock acquired with tryLockFile
+func unlockFile(f *os.File) error {
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+		return fmt.Errorf("error releasing lock %s: %w", f.Name(), err)
+	}
+
+	return nil
+}
@@ -1,26 +1,47 @@
 package orchestrator
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
-	"os/exec"
+	"os"
+	"sync"
 
 	"github.com/yago-123/minikube-testing/pkg/client"
-
-	"github.com/google/uuid"
 )
 
+const minikubeBinary = "minikube"
+
+// make sure that Minikube implements the Orchestrator interface
+var _ Orchestrator = (*Minikube)(nil)
+
 type Minikube struct {
 	stdout  io.Writer
 	stderr  io.Writer
 	profile string
+
+	runner    CommandRunner
+	readiness client.Readiness
+	cli       client.Client
+
+	// mu protects the background processes (e.g. mounts) that must be stopped before deleting the cluster and the
+	// nodes stopped with StopNode, which are not expected to be ready
+	mu           sync.Mutex
+	processes    []*backgroundProcess
+	stoppedNodes map[string]struct{}
 }
 
+// NewMinikube creates the orchestrator for a new profile with a generated name, see Reap for cleaning up the profiles
+// leaked by processes that died before deleting them
 func NewMinikube(stdout, stderr io.Writer) *Minikube {
 	return &Minikube{
 		stdout:  stdout,
 		stderr:  stderr,
-		profile: uuid.NewString(),
+		profile: generateName(ProfilePrefix),
+
+		runner:    ExecRunner{},
+		readiness: client.DefaultReadiness(),
 	}
 }
 
@@ -29,71 +50,147 @@ func NewMinikubeWithProfile(stdout, stderr io.Writer, profile string) *Minikube
 		stdout:  stdout,
 		stderr:  stderr,
 		profile: profile,
+
+		runner:    ExecRunner{},
+		readiness: client.DefaultReadiness(),
 	}
 }
 
-func (mc *Minikube) Create(version string, nodes, cpusPerNode, memoryPerNode uint) (client.Client, error) {
-	cmd := exec.Command(
-		"minikube",
-		"start",
-		fmt.Sprintf("--kubernetes-version=%s", version),
-		fmt.Sprintf("--nodes=%d", nodes),
-		fmt.Sprintf("--cpus=%d", cpusPerNode),
-		fmt.Sprintf("--memory=%d", memoryPerNode),
-		fmt.Sprintf("--profile=%s", mc.profile),
-	)
+// WithReadiness replaces the readiness definition that Create waits for once the cluster has started
+func (mc *Minikube) WithReadiness(readiness client.Readiness) *Minikube {
+	mc.readiness = readiness
+	return mc
+}
 
-	cmd.Stdout = mc.stdout
-	cmd.Stderr = mc.stderr
+// WithRunner replaces the runner used to execute minikube, see Recorder and Replayer for running without the binary
+func (mc *Minikube) WithRunner(runner CommandRunner) *Minikube {
+	mc.runner = runner
+	return mc
+}
 
-	err := cmd.Run()
-	if err != nil {
+// Create starts the minikube cluster with the default settings for the rest of the spec, see CreateWithSpec
+func (mc *Minikube) Create(ctx context.Context, version string, nodes, cpusPerNode, memoryPerNode uint) (client.Client, error) {
+	return mc.CreateWithSpec(ctx, ClusterSpec{
+		KubernetesVersion: version,
+		Nodes:             nodes,
+		CPUs:              cpusPerNode,
+		Memory:            memoryPerNode,
+	})
+}
+
+// CreateWithSpec starts the minikube cluster and blocks until it meets the readiness definition or the context
+// finishes. The credentials are written into a kubeconfig file scoped to the profile, so the developer kubeconfig is
+// never modified and the client returned is always bound to this profile. The spec is kept next to the kubeconfig
+func (mc *Minikube) CreateWithSpec(ctx context.Context, spec ClusterSpec) (client.Client, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(kubeconfigDir(), 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create kubeconfig directory: %w", err)
+	}
+
+	if err := saveClusterSpec(mc.specPath(), spec); err != nil {
+		return nil, err
+	}
+
+	if err := mc.run(ctx, spec.StartArgs(mc.profile)...); err != nil {
 		return nil, fmt.Errorf("failed to start minikube: %w", err)
 	}
 
-	cli, err := client.NewClient()
+	// minikube names the context after the profile
+	cli, err := client.NewClientWithKubeconfig(mc.Kubeconfig(), mc.profile)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create client: %w", err)
 	}
 
+	if err = cli.WaitForReadiness(ctx, mc.readiness); err != nil {
+		return nil, fmt.Errorf("cluster not ready: %w", err)
+	}
+	mc.cli = cli
+
 	return cli, nil
 }
 
-func (mc *Minikube) LoadImage(image, tag string) error {
-	cmd := exec.Command(
-		"minikube",
-		"image",
-		"load",
-		"--profile",
-		mc.profile,
-		fmt.Sprintf("%s:%s", image, tag),
-	)
+func (mc *Minikube) LoadImage(ctx context.Context, image, tag string) error {
+	return mc.loadImage(ctx, fmt.Sprintf("%s:%s", image, tag))
+}
 
-	cmd.Stdout = mc.stdout
-	cmd.Stderr = mc.stderr
+func (mc *Minikube) Delete(ctx context.Context) error {
+	mc.stopBackground()
 
-	err := cmd.Run()
+	err := mc.run(
+		ctx,
+		"delete",
+		fmt.Sprintf("--profile=%s", mc.profile),
+	)
 	if err != nil {
-		return fmt.Errorf("failed to load image %s: %w", fmt.Sprintf("%s:%s", image, tag), err)
+		return fmt.Errorf("failed to delete minikube: %w", err)
 	}
 
-	return nil
+	return removeClusterFiles(mc.Kubeconfig(), mc.specPath(), clusterFilePath(mc.profile, imagesRecordName))
 }
 
-func (mc *Minikube) Delete() error {
-	cmd := exec.Command(
-		"minikube",
-		"delete",
-		fmt.Sprintf("--profile=%s", mc.profile),
-	)
+// Kubeconfig returns the path of the kubeconfig file that contains the credentials of the profile
+func (mc *Minikube) Kubeconfig() string {
+	return clusterFilePath(mc.profile, "kubeconfig")
+}
 
-	cmd.Stdout = mc.stdout
-	cmd.Stderr = mc.stderr
+// Spec returns the spec requested when the profile was created, it's read from disk so that it's also available
+// for profiles created by other processes
+func (mc *Minikube) Spec() (ClusterSpec, error) {
+	return LoadClusterSpec(mc.specPath())
+}
+
+// specPath returns the path of the file that holds the spec requested for the profile
+func (mc *Minikube) specPath() string {
+	return clusterFilePath(mc.profile, "spec.yaml")
+}
 
-	err := cmd.Run()
+// client returns the client bound to the profile, it's created from the profile kubeconfig in case the cluster was
+// not created by this instance
+func (mc *Minikube) client() (client.Client, error) {
+	if mc.cli != nil {
+		return mc.cli, nil
+	}
+
+	cli, err := client.NewClientWithKubeconfig(mc.Kubeconfig(), mc.profile)
 	if err != nil {
-		return fmt.Errorf("failed to delete minikube: %w", err)
+		return nil, fmt.Errorf("unable to create client: %w", err)
 	}
+	mc.cli = cli
+
+	return cli, nil
+}
+
+// run executes minikube with the given arguments
+func (mc *Minikube) run(ctx context.Context, args ...string) error {
+	return mc.runner.Run(ctx, Command{
+		Name:   minikubeBinary,
+		Args:   args,
+		Env:    mc.env(),
+		Stdout: mc.stdout,
+		Stderr: mc.stderr,
+	})
+}
+
+// output executes minikube with the given arguments and returns its standard output. The output is returned even if
+// the command fails, some commands (e.g. status) report the state of the cluster through the exit code
+func (mc *Minikube) output(ctx context.Context, args ...string) ([]byte, error) {
+	out := new(bytes.Buffer)
+	err := mc.runner.Run(ctx, Command{
+		Name:   minikubeBinary,
+		Args:   args,
+		Env:    mc.env(),
+		Stdout: out,
+		Stderr: mc.stderr,
+	})
+
+	return out.Bytes(), err
+}
 
-	return nil
+// env returns the environment variables used for every minikube command, KUBECONFIG points to the kubeconfig scoped
+// to the profile so that minikube reads and writes the credentials there instead of ${HOME}/.kube/config
+func (mc *Minikube) env() []string {
+	return []string{fmt.Sprintf("KUBECONFIG=%s", mc.Kubeconfig())}
 }
@@ -0,0 +1,102 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+// mountReadyMarker is printed by minikube mount once the directory is available inside the nodes
+const mountReadyMarker = "Successfully mounted"
+
+// MountHandle represents a host directory mounted into the nodes of the cluster
+type MountHandle struct {
+	HostPath string
+	NodePath string
+
+	process *backgroundProcess
+}
+
+// Unmount stops the mount process so that minikube removes the mount from the nodes, returns the error of the process
+// if it had already exited on its own
+func (m *MountHandle) Unmount() error {
+	if err := m.process.stop(); err != nil {
+		return fmt.Errorf("mount %s:%s failed: %w", m.HostPath, m.NodePath, err)
+	}
+
+	return nil
+}
+
+// Done returns a channel that is closed once the mount is gone, either because it was unmounted or because the mount
+// process exited
+func (m *MountHandle) Done() <-chan struct{} {
+	return m.process.exited()
+}
+
+// Mount makes the host directory available in the given path of the nodes, running minikube mount in background.
+// Blocks until the directory is mounted. The directory is unmounted with MountHandle.Unmount, when the context
+// finishes or when the cluster is deleted
+func (mc *Minikube) Mount(ctx context.Context, hostPath, nodePath string) (*MountHandle, error) {
+	if !filepath.IsAbs(hostPath) || !filepath.IsAbs(nodePath) {
+		return nil, fmt.Errorf("mount paths must be absolute, got %s:%s", hostPath, nodePath)
+	}
+
+	process, err := startBackground(ctx, mc.runner, Command{
+		Name: minikubeBinary,
+		Args: []string{
+			"mount",
+			fmt.Sprintf("%s:%s", hostPath, nodePath),
+			fmt.Sprintf("--profile=%s", mc.profile),
+		},
+		Env:    mc.env(),
+		Stdout: mc.stdout,
+		Stderr: mc.stderr,
+	}, mountReadyMarker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mount %s:%s: %w", hostPath, nodePath, err)
+	}
+
+	mc.track(process)
+
+	return &MountHandle{HostPath: hostPath, NodePath: nodePath, process: process}, nil
+}
+
+// track registers the background process so that it's stopped before deleting the cluster. The process is forgotten
+// once it exits, whether it was stopped by the caller or exited on its own, so that clusters that remount many times
+// don't accumulate them
+func (mc *Minikube) track(process *backgroundProcess) {
+	mc.mu.Lock()
+	mc.processes = append(mc.processes, process)
+	mc.mu.Unlock()
+
+	go func() {
+		<-process.exited()
+
+		mc.mu.Lock()
+		defer mc.mu.Unlock()
+
+		mc.processes = slices.DeleteFunc(mc.processes, func(p *backgroundProcess) bool { return p == process })
+	}()
+}
+
+// trackedProcesses returns the number of background processes that are still running
+func (mc *Minikube) trackedProcesses() int {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	return len(mc.processes)
+}
+
+// stopBackground stops all the background processes started for the cluster, processes that exited on their own
+// are not considered an error at this point
+func (mc *Minikube) stopBackground() {
+	mc.mu.Lock()
+	processes := mc.processes
+	mc.processes = nil
+	mc.mu.Unlock()
+
+	for _, process := range processes {
+		_ = process.stop()
+	}
+}
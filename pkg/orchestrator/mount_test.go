@@ -0,0 +1,108 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mountRunner simulates minikube mount, prints the ready marker and keeps running until interrupted. The runner is
+// executed in a background goroutine, the arguments of each mount are sent back so that the test asserts them
+func mountRunner() (CommandRunner, <-chan []string) {
+	args := make(chan []string, 1)
+
+	return runnerFunc(func(ctx context.Context, cmd Command) error {
+		if cmd.Args[0] != "mount" {
+			return nil
+		}
+
+		args <- cmd.Args
+		if _, err := io.WriteString(cmd.Stdout, "* Mounting host path /tmp/fixtures into VM as /fixtures ...\n"+
+			"* Successfully mounted /tmp/fixtures to /fixtures\n"); err != nil {
+			return err
+		}
+
+		<-ctx.Done()
+		return ctx.Err()
+	}), args
+}
+
+// requireMountArgs checks the arguments of the next mount executed by mountRunner
+func requireMountArgs(t *testing.T, args <-chan []string) {
+	t.Helper()
+
+	require.Equal(t, []string{"mount", "/tmp/fixtures:/fixtures", "--profile=my-profile"}, <-args)
+}
+
+func TestMount(t *testing.T) {
+	runner, args := mountRunner()
+	mk := NewMinikubeWithProfile(io.Discard, io.Discard, "my-profile").WithRunner(runner)
+
+	mount, err := mk.Mount(context.Background(), "/tmp/fixtures", "/fixtures")
+	require.NoError(t, err)
+	requireMountArgs(t, args)
+
+	select {
+	case <-mount.Done():
+		t.Fatal("mount finished before being unmounted")
+	default:
+	}
+
+	require.Equal(t, 1, mk.trackedProcesses())
+
+	require.NoError(t, mount.Unmount())
+	require.NoError(t, mount.Unmount())
+	<-mount.Done()
+
+	// unmounted processes are no longer tracked by the cluster
+	require.Eventually(t, func() bool { return mk.trackedProcesses() == 0 }, time.Second, 10*time.Millisecond)
+
+	_, err = mk.Mount(context.Background(), "fixtures", "/fixtures")
+	require.Error(t, err)
+}
+
+func TestMountContextCancelled(t *testing.T) {
+	runner, args := mountRunner()
+	mk := NewMinikubeWithProfile(io.Discard, io.Discard, "my-profile").WithRunner(runner)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mount, err := mk.Mount(ctx, "/tmp/fixtures", "/fixtures")
+	require.NoError(t, err)
+	requireMountArgs(t, args)
+
+	cancel()
+	select {
+	case <-mount.Done():
+	case <-time.After(time.Second):
+		t.Fatal("mount not stopped after cancelling the context")
+	}
+	require.Eventually(t, func() bool { return mk.trackedProcesses() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestMountStoppedOnDelete(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	runner, args := mountRunner()
+	mk := NewMinikubeWithProfile(io.Discard, io.Discard, "my-profile").WithRunner(runner)
+
+	mount, err := mk.Mount(context.Background(), "/tmp/fixtures", "/fixtures")
+	require.NoError(t, err)
+	requireMountArgs(t, args)
+
+	require.NoError(t, mk.Delete(context.Background()))
+	<-mount.Done()
+}
+
+func TestMountExitsBeforeReady(t *testing.T) {
+	runner := runnerFunc(func(_ context.Context, _ Command) error {
+		return errors.New("exit status 1")
+	})
+	mk := NewMinikubeWithProfile(io.Discard, io.Discard, "my-profile").WithRunner(runner)
+
+	_, err := mk.Mount(context.Background(), "/tmp/fixtures", "/fixtures")
+	require.ErrorContains(t, err, "exited before being ready")
+}
@@ -0,0 +1,145 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yago-123/minikube-testing/pkg/client"
+)
+
+// ProvisionRollbackTimeout is the time given to delete the clusters when provisioning fails, the context used for
+// provisioning may be expired by then
+const ProvisionRollbackTimeout = 2 * time.Minute
+
+// ProvisionOptions contains the settings shared by all the clusters provisioned together
+type ProvisionOptions struct {
+	// Runner executes minikube for all the clusters, defaults to ExecRunner
+	Runner CommandRunner
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ClusterMember is one of the clusters provisioned together, the client is bound to its own profile
+type ClusterMember struct {
+	Minikube *Minikube
+	Client   client.Client
+}
+
+// Clusters is a group of minikube clusters that are created and deleted together
+type Clusters struct {
+	Members []ClusterMember
+}
+
+// ProvisioningError is returned when any of the clusters fails to be created, contains the error of each cluster
+// that failed indexed by profile together with the errors found while rolling back
+type ProvisioningError struct {
+	Failures    map[string]error
+	RollbackErr error
+}
+
+func (e *ProvisioningError) Error() string {
+	profiles := []string{}
+	for profile := range e.Failures {
+		profiles = append(profiles, profile)
+	}
+	// map iteration is random, sort to keep the message stable
+	sort.Strings(profiles)
+
+	failures := []string{}
+	for _, profile := range profiles {
+		failures = append(failures, fmt.Sprintf("%s: %v", profile, e.Failures[profile]))
+	}
+
+	msg := fmt.Sprintf("failed to provision %d clusters: %s", len(failures), strings.Join(failures, "; "))
+	if e.RollbackErr != nil {
+		msg = fmt.Sprintf("%s, rollback failed: %v", msg, e.RollbackErr)
+	}
+
+	return msg
+}
+
+// ProvisionClusters creates a cluster for each spec concurrently, each one with its own random profile and client. If
+// any of them fails the creation of the rest is cancelled, and once all of them have returned they are deleted so that
+// no cluster is left behind. The clusters cancelled are reported as failures too
+func ProvisionClusters(ctx context.Context, specs []ClusterSpec, opts ProvisionOptions) (*Clusters, error) {
+	if opts.Runner == nil {
+		opts.Runner = ExecRunner{}
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = io.Discard
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = io.Discard
+	}
+
+	// fail before creating any cluster if one of the specs can't be provisioned
+	for i, spec := range specs {
+		if err := spec.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid spec for cluster %d: %w", i, err)
+		}
+	}
+
+	// the first failure cancels the clusters still being created, there is no point in waiting for them
+	provisionCtx, cancelProvision := context.WithCancel(ctx)
+	defer cancelProvision()
+
+	clusters := &Clusters{Members: make([]ClusterMember, len(specs))}
+	errs := make([]error, len(specs))
+	wg := sync.WaitGroup{}
+
+	for i, spec := range specs {
+		mk := NewMinikube(opts.Stdout, opts.Stderr).WithRunner(opts.Runner)
+		clusters.Members[i] = ClusterMember{Minikube: mk}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clusters.Members[i].Client, errs[i] = mk.CreateWithSpec(provisionCtx, spec)
+			if errs[i] != nil {
+				cancelProvision()
+			}
+		}()
+	}
+	wg.Wait()
+
+	failures := map[string]error{}
+	for i, err := range errs {
+		if err != nil {
+			failures[clusters.Members[i].Minikube.profile] = err
+		}
+	}
+
+	if len(failures) == 0 {
+		return clusters, nil
+	}
+
+	// clusters that failed can be partially created, delete all of them
+	rollbackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), ProvisionRollbackTimeout)
+	defer cancel()
+
+	return nil, &ProvisioningError{Failures: failures, RollbackErr: clusters.Delete(rollbackCtx)}
+}
+
+// Delete deletes all the clusters concurrently, returns the errors of all the clusters that could not be deleted
+func (c *Clusters) Delete(ctx context.Context) error {
+	errs := make([]error, len(c.Members))
+	wg := sync.WaitGroup{}
+
+	for i, member := range c.Members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = member.Minikube.Delete(ctx)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
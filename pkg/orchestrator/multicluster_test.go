@@ -0,0 +1,111 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisionClustersRollback(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	mu := sync.Mutex{}
+	deleted := []string{}
+
+	runner := runnerFunc(func(_ context.Context, cmd Command) error {
+		switch cmd.Args[0] {
+		case "start":
+			if slices.Contains(cmd.Args, "--nodes=2") {
+				return errors.New("exit status 80")
+			}
+			// the cluster starts but the kubeconfig is never written, so the client can't be created
+			return nil
+		case "delete":
+			mu.Lock()
+			deleted = append(deleted, strings.TrimPrefix(cmd.Args[1], "--profile="))
+			mu.Unlock()
+			return nil
+		default:
+			return errors.New("unexpected command")
+		}
+	})
+
+	specs := []ClusterSpec{
+		{KubernetesVersion: "1.30.0", Nodes: 1, CPUs: 2, Memory: 2048},
+		{KubernetesVersion: "1.30.0", Nodes: 2, CPUs: 2, Memory: 2048},
+	}
+
+	clusters, err := ProvisionClusters(context.Background(), specs, ProvisionOptions{Runner: runner})
+	require.Nil(t, clusters)
+
+	var provisioningErr *ProvisioningError
+	require.ErrorAs(t, err, &provisioningErr)
+	require.Len(t, provisioningErr.Failures, 2)
+	require.NoError(t, provisioningErr.RollbackErr)
+	require.Len(t, deleted, 2)
+
+	for _, profile := range deleted {
+		require.Contains(t, provisioningErr.Failures, profile)
+	}
+}
+
+func TestProvisionClustersCancelOnFailure(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	runner := runnerFunc(func(ctx context.Context, cmd Command) error {
+		switch {
+		case cmd.Args[0] == "start" && slices.Contains(cmd.Args, "--nodes=2"):
+			return errors.New("exit status 80")
+		case cmd.Args[0] == "start":
+			// keeps starting until cancelled
+			<-ctx.Done()
+			return ctx.Err()
+		default:
+			return nil
+		}
+	})
+
+	specs := []ClusterSpec{
+		{KubernetesVersion: "1.30.0", Nodes: 1, CPUs: 2, Memory: 2048},
+		{KubernetesVersion: "1.30.0", Nodes: 2, CPUs: 2, Memory: 2048},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := ProvisionClusters(ctx, specs, ProvisionOptions{Runner: runner})
+
+	var provisioningErr *ProvisioningError
+	require.ErrorAs(t, err, &provisioningErr)
+	require.Len(t, provisioningErr.Failures, 2)
+
+	// the cluster still starting is cancelled by the failure of the other one, not by the deadline
+	cancelled := 0
+	for _, failure := range provisioningErr.Failures {
+		if errors.Is(failure, context.Canceled) {
+			cancelled++
+		}
+	}
+	require.Equal(t, 1, cancelled)
+	require.NoError(t, ctx.Err())
+}
+
+func TestProvisionClustersInvalidSpec(t *testing.T) {
+	runner := runnerFunc(func(_ context.Context, _ Command) error {
+		return errors.New("no command expected")
+	})
+
+	specs := []ClusterSpec{
+		{KubernetesVersion: "1.30.0", Nodes: 1, CPUs: 2, Memory: 2048},
+		{KubernetesVersion: "1.30.0", Nodes: 1, CPUs: 1, Memory: 2048},
+	}
+
+	_, err := ProvisionClusters(context.Background(), specs, ProvisionOptions{Runner: runner})
+	require.ErrorContains(t, err, "invalid spec for cluster 1")
+}
@@ -0,0 +1,161 @@
+package orchestrator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/yago-123/minikube-testing/pkg/client"
+)
+
+// Node represents a node of the cluster as reported by minikube node list
+type Node struct {
+	Name string
+	IP   string
+}
+
+// Nodes returns the nodes of the cluster, including the stopped ones
+func (mc *Minikube) Nodes(ctx context.Context) ([]Node, error) {
+	out, err := mc.output(ctx, "node", "list", fmt.Sprintf("--profile=%s", mc.profile))
+	if err != nil {
+		return []Node{}, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	return parseNodes(out)
+}
+
+// AddNode adds a worker node to the cluster and blocks until the cluster meets the readiness definition again, nodes
+// stopped with StopNode are not required to be ready. minikube picks the name of the node, the node returned is the
+// one that was not present before
+func (mc *Minikube) AddNode(ctx context.Context) (Node, error) {
+	before, err := mc.Nodes(ctx)
+	if err != nil {
+		return Node{}, err
+	}
+
+	if err = mc.run(ctx, "node", "add", "--worker", fmt.Sprintf("--profile=%s", mc.profile)); err != nil {
+		return Node{}, fmt.Errorf("failed to add node: %w", err)
+	}
+
+	after, err := mc.Nodes(ctx)
+	if err != nil {
+		return Node{}, err
+	}
+
+	added, found := Node{}, false
+	for _, node := range after {
+		if !slices.ContainsFunc(before, func(n Node) bool { return n.Name == node.Name }) {
+			added, found = node, true
+		}
+	}
+
+	if !found {
+		return Node{}, errors.New("node added but not listed")
+	}
+
+	if err = mc.waitForReadiness(ctx); err != nil {
+		return Node{}, err
+	}
+
+	return added, nil
+}
+
+// DeleteNode removes the node from the cluster, the pods running on it are evicted
+func (mc *Minikube) DeleteNode(ctx context.Context, name string) error {
+	if err := mc.run(ctx, "node", "delete", name, fmt.Sprintf("--profile=%s", mc.profile)); err != nil {
+		return fmt.Errorf("failed to delete node %s: %w", name, err)
+	}
+
+	mc.setNodeStopped(name, false)
+
+	return nil
+}
+
+// StopNode shuts down the node without removing it from the cluster, simulating a node failure. The node stays
+// NotReady until it's started again, meanwhile the node is not required to be ready by the operations that wait for
+// the cluster (AddNode, StartNode, Start...)
+func (mc *Minikube) StopNode(ctx context.Context, name string) error {
+	if err := mc.run(ctx, "node", "stop", name, fmt.Sprintf("--profile=%s", mc.profile)); err != nil {
+		return fmt.Errorf("failed to stop node %s: %w", name, err)
+	}
+
+	mc.setNodeStopped(name, true)
+
+	return nil
+}
+
+// StartNode starts a node stopped with StopNode and blocks until the cluster meets the readiness definition again,
+// including the node started. Other nodes stopped with StopNode are not required to be ready
+func (mc *Minikube) StartNode(ctx context.Context, name string) error {
+	if err := mc.run(ctx, "node", "start", name, fmt.Sprintf("--profile=%s", mc.profile)); err != nil {
+		return fmt.Errorf("failed to start node %s: %w", name, err)
+	}
+
+	mc.setNodeStopped(name, false)
+
+	return mc.waitForReadiness(ctx)
+}
+
+// setNodeStopped records whether the node was stopped on purpose
+func (mc *Minikube) setNodeStopped(name string, stopped bool) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if mc.stoppedNodes == nil {
+		mc.stoppedNodes = map[string]struct{}{}
+	}
+
+	if stopped {
+		mc.stoppedNodes[name] = struct{}{}
+	} else {
+		delete(mc.stoppedNodes, name)
+	}
+}
+
+// nodeReadiness returns the readiness definition of the cluster ignoring the nodes stopped on purpose
+func (mc *Minikube) nodeReadiness() client.Readiness {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	ignored := []string{}
+	for name := range mc.stoppedNodes {
+		ignored = append(ignored, name)
+	}
+	// map iteration is random, sort to keep the readiness stable
+	sort.Strings(ignored)
+
+	readiness := mc.readiness
+	readiness.IgnoredNodes = append(slices.Clone(readiness.IgnoredNodes), ignored...)
+
+	return readiness
+}
+
+// parseNodes parses the output of minikube node list, one node per line with its name and IP
+func parseNodes(out []byte) ([]Node, error) {
+	nodes := []Node{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch len(fields) {
+		case 0:
+			continue
+		case 1:
+			// stopped nodes may not report an IP
+			nodes = append(nodes, Node{Name: fields[0]})
+		default:
+			nodes = append(nodes, Node{Name: fields[0], IP: fields[1]})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return []Node{}, fmt.Errorf("error reading nodes: %w", err)
+	}
+
+	return nodes, nil
+}
@@ -0,0 +1,76 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yago-123/minikube-testing/pkg/client"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: nodes-profile
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: nodes-profile
+  context:
+    cluster: nodes-profile
+    user: nodes-profile
+current-context: nodes-profile
+users:
+- name: nodes-profile
+  user:
+    token: test
+`
+
+func TestParseNodes(t *testing.T) {
+	nodes, err := parseNodes([]byte("my-profile\t192.168.49.2\nmy-profile-m02\t192.168.49.3\nmy-profile-m03\n\n"))
+	require.NoError(t, err)
+	require.Equal(t, []Node{
+		{Name: "my-profile", IP: "192.168.49.2"},
+		{Name: "my-profile-m02", IP: "192.168.49.3"},
+		{Name: "my-profile-m03"},
+	}, nodes)
+}
+
+func TestNodeLifecycle(t *testing.T) {
+	replayer := NewReplayer([]CommandRecord{
+		{Name: "minikube", Args: []string{"node", "list", "--profile=nodes-profile"}, Stdout: "nodes-profile\t192.168.49.2\n"},
+		{Name: "minikube", Args: []string{"node", "add", "--worker", "--profile=nodes-profile"}},
+		{
+			Name:   "minikube",
+			Args:   []string{"node", "list", "--profile=nodes-profile"},
+			Stdout: "nodes-profile\t192.168.49.2\nnodes-profile-m02\t192.168.49.3\n",
+		},
+		{Name: "minikube", Args: []string{"node", "stop", "nodes-profile-m02", "--profile=nodes-profile"}},
+		{Name: "minikube", Args: []string{"node", "start", "nodes-profile-m02", "--profile=nodes-profile"}},
+		{Name: "minikube", Args: []string{"node", "delete", "nodes-profile-m02", "--profile=nodes-profile"}},
+	})
+
+	// no readiness checks, the client is built from the kubeconfig but never reaches the cluster
+	mk := NewMinikubeWithProfile(io.Discard, io.Discard, "nodes-profile").
+		WithRunner(replayer).
+		WithReadiness(client.Readiness{Checks: []client.ReadinessCheck{}})
+
+	// the kubeconfig directory lives in the temporary directory, keep the files of the test out of the shared one
+	t.Setenv("TMPDIR", t.TempDir())
+	require.NoError(t, os.MkdirAll(kubeconfigDir(), 0o700))
+	require.NoError(t, os.WriteFile(mk.Kubeconfig(), []byte(testKubeconfig), 0o600))
+
+	node, err := mk.AddNode(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, Node{Name: "nodes-profile-m02", IP: "192.168.49.3"}, node)
+
+	// stopped nodes are not waited for until they are started again
+	require.NoError(t, mk.StopNode(context.Background(), node.Name))
+	require.Equal(t, []string{node.Name}, mk.nodeReadiness().IgnoredNodes)
+	require.NoError(t, mk.StartNode(context.Background(), node.Name))
+	require.Empty(t, mk.nodeReadiness().IgnoredNodes)
+	require.NoError(t, mk.DeleteNode(context.Background(), node.Name))
+	require.Empty(t, replayer.Remaining())
+}
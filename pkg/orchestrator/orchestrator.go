@@ -1,9 +1,116 @@
 package orchestrator
 
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/yago-123/minikube-testing/pkg/client"
+)
+
 type Orchestrator interface {
-	Create(version string, nodes, cpusPerNode, memoryPerNode uint) error
+	Create(ctx context.Context, version string, nodes, cpusPerNode, memoryPerNode uint) (client.Client, error)
 	// todo(): add method for uploading app to save bandwith (there must be some way via Minikube API)
 	// todo(): check minikube command line
-	LoadImage(image, tag string) error
-	Delete() error
+	LoadImage(ctx context.Context, image, tag string) error
+	Delete(ctx context.Context) error
+}
+
+type Backend string
+
+const (
+	BackendMinikube Backend = "minikube"
+	BackendKind     Backend = "kind"
+	BackendK3d      Backend = "k3d"
+)
+
+// NewOrchestrator creates the orchestrator for the given backend, allows tests written against the Orchestrator
+// interface to switch backends through configuration
+func NewOrchestrator(backend Backend, stdout, stderr io.Writer) (Orchestrator, error) {
+	switch backend {
+	case BackendMinikube:
+		return NewMinikube(stdout, stderr), nil
+	case BackendKind:
+		return NewKind(stdout, stderr), nil
+	case BackendK3d:
+		return NewK3d(stdout, stderr), nil
+	default:
+		return nil, fmt.Errorf("unsupported orchestrator backend %q", backend)
+	}
+}
+
+// imagesRecordName is the name of the file that records the host images loaded into each cluster
+const imagesRecordName = "images"
+
+// kubeconfigDir returns the directory that holds the kubeconfig files scoped to each cluster
+func kubeconfigDir() string {
+	return filepath.Join(os.TempDir(), "minikube-testing")
+}
+
+// clusterFilePath returns the path of a file scoped to the given cluster inside the kubeconfig directory
+func clusterFilePath(cluster, name string) string {
+	return filepath.Join(kubeconfigDir(), fmt.Sprintf("%s.%s", cluster, name))
+}
+
+// recordImage adds the host image to the images loaded into the cluster, so that the reaper can remove them if the
+// cluster leaks. Each image is appended with a single write, concurrent loads don't interleave
+func recordImage(cluster, ref string) error {
+	if err := os.MkdirAll(kubeconfigDir(), 0o700); err != nil {
+		return fmt.Errorf("unable to create kubeconfig directory: %w", err)
+	}
+
+	f, err := os.OpenFile(clusterFilePath(cluster, imagesRecordName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening images record: %w", err)
+	}
+	defer f.Close()
+
+	if _, err = f.WriteString(ref + "\n"); err != nil {
+		return fmt.Errorf("error recording image %s: %w", ref, err)
+	}
+
+	return nil
+}
+
+// recordedImages returns the host images loaded into the cluster without duplicates, empty if none was recorded
+func recordedImages(cluster string) ([]string, error) {
+	f, err := os.Open(clusterFilePath(cluster, imagesRecordName))
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return []string{}, fmt.Errorf("error opening images record: %w", err)
+	}
+	defer f.Close()
+
+	refs := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if ref := strings.TrimSpace(scanner.Text()); ref != "" && !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return []string{}, fmt.Errorf("error reading images record: %w", err)
+	}
+
+	return refs, nil
+}
+
+// removeClusterFiles removes the files scoped to the cluster, missing files are ignored
+func removeClusterFiles(paths ...string) error {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,392 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yago-123/minikube-testing/pkg/client"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// PoolProfilePrefix prefixes the profiles of the pool, named <prefix>-<spec hash>-<index>. The names must be
+	// stable to reuse the clusters, so instead of the creation time the reaper relies on the state of each cluster
+	PoolProfilePrefix   = ProfilePrefix + "-pool"
+	PoolDefaultIdleTTL  = 2 * time.Hour
+	PoolRetryInterval   = 5 * time.Second
+	PoolReuseTimeout    = 2 * time.Minute
+	PoolDefaultClusters = 1
+
+	// specHashLength is the number of hex characters of the spec hash used in profile names
+	specHashLength = 12
+	lockExtension  = "lock"
+	stateExtension = "json"
+)
+
+// PoolOptions contains the settings of the cluster pool
+type PoolOptions struct {
+	// Dir holds the lock and state files of the clusters of the pool, defaults to a directory inside the temp dir
+	Dir string
+	// IdleTTL is the time after which a cluster that has not been leased is deleted, defaults to PoolDefaultIdleTTL
+	IdleTTL time.Duration
+	// MaxClustersPerSpec is the number of clusters with the same spec that can be leased at the same time, defaults
+	// to PoolDefaultClusters
+	MaxClustersPerSpec int
+	// Namespaces are deleted and recreated every time a cluster is leased, so that each lease starts clean
+	Namespaces []string
+	// Runner executes minikube for the clusters of the pool, defaults to ExecRunner
+	Runner CommandRunner
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Pool keeps minikube clusters around between test runs and leases them to the callers that request the same spec.
+// Leases are protected with file locks so that processes of the same host can share the pool
+type Pool struct {
+	opts PoolOptions
+}
+
+// Lease represents exclusive access to a cluster of the pool until it's released
+type Lease struct {
+	Minikube *Minikube
+	Client   client.Client
+
+	lock  *os.File
+	state string
+}
+
+// poolState is persisted next to the lock of each cluster to track when it was used for the last time
+type poolState struct {
+	Profile  string    `json:"profile"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+func NewPool(opts PoolOptions) *Pool {
+	if opts.Dir == "" {
+		opts.Dir = filepath.Join(kubeconfigDir(), PoolProfilePrefix)
+	}
+	if opts.IdleTTL == 0 {
+		opts.IdleTTL = PoolDefaultIdleTTL
+	}
+	if opts.MaxClustersPerSpec == 0 {
+		opts.MaxClustersPerSpec = PoolDefaultClusters
+	}
+	if opts.Runner == nil {
+		opts.Runner = ExecRunner{}
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = io.Discard
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = io.Discard
+	}
+
+	return &Pool{opts: opts}
+}
+
+// Acquire leases a cluster that matches the spec. A healthy cluster without drift is reused after resetting its
+// namespaces, otherwise the cluster is recreated. If all the clusters of the spec are leased, it waits until one is
+// released or the context finishes
+func (p *Pool) Acquire(ctx context.Context, spec ClusterSpec) (*Lease, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(p.opts.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create pool directory: %w", err)
+	}
+
+	if err := p.Evict(ctx); err != nil {
+		return nil, err
+	}
+
+	hash, err := specHash(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		for i := range p.opts.MaxClustersPerSpec {
+			profile := fmt.Sprintf("%s-%s-%d", PoolProfilePrefix, hash, i)
+
+			lock, acquired, errLock := tryLockFile(p.path(profile, lockExtension))
+			if errLock != nil {
+				return nil, errLock
+			}
+			if !acquired {
+				continue
+			}
+
+			lease, errLease := p.lease(ctx, profile, spec, lock)
+			if errLease != nil {
+				_ = unlockFile(lock)
+				return nil, errLease
+			}
+
+			return lease, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("all clusters of the pool are leased: %w", ctx.Err())
+		case <-time.After(PoolRetryInterval):
+		}
+	}
+}
+
+// Release updates the last time the cluster was used and releases the lock so that other callers can lease it
+func (l *Lease) Release() error {
+	if err := writePoolState(l.state, poolState{Profile: l.Minikube.profile, LastUsed: time.Now()}); err != nil {
+		_ = unlockFile(l.lock)
+		return err
+	}
+
+	return unlockFile(l.lock)
+}
+
+// Evict deletes the clusters that have not been leased for longer than the idle TTL together with their lock and
+// state files. Clusters leased at the moment are skipped. It doesn't need any cluster to be acquired, so it can be
+// called from the teardown of the tests or from the reaper
+func (p *Pool) Evict(ctx context.Context) error {
+	_, err := p.evict(ctx, p.opts.IdleTTL, p.deleteCluster)
+	return err
+}
+
+// EvictAll deletes all the clusters of the pool that are not leased at the moment, regardless of when they were used
+func (p *Pool) EvictAll(ctx context.Context) error {
+	_, err := p.evict(ctx, 0, p.deleteCluster)
+	return err
+}
+
+// evictFunc deletes the cluster of the profile, returns false if the cluster was kept (e.g. in dry run mode)
+type evictFunc func(ctx context.Context, profile string) (bool, error)
+
+// evict deletes with deleteFn the clusters that have not been leased for longer than idleTTL, clusters leased are
+// skipped. Returns the profiles of the idle clusters
+func (p *Pool) evict(ctx context.Context, idleTTL time.Duration, deleteFn evictFunc) ([]string, error) {
+	states, err := filepath.Glob(filepath.Join(p.opts.Dir, fmt.Sprintf("*.%s", stateExtension)))
+	if err != nil {
+		return []string{}, fmt.Errorf("error listing pool clusters: %w", err)
+	}
+
+	evicted := []string{}
+	for _, statePath := range states {
+		profile := strings.TrimSuffix(filepath.Base(statePath), fmt.Sprintf(".%s", stateExtension))
+
+		lock, acquired, errLock := tryLockFile(p.path(profile, lockExtension))
+		if errLock != nil {
+			return evicted, errLock
+		}
+		if !acquired {
+			continue
+		}
+
+		idle, errEvict := p.evictIfIdle(ctx, profile, statePath, idleTTL, deleteFn)
+		if errUnlock := unlockFile(lock); errEvict == nil {
+			errEvict = errUnlock
+		}
+		if errEvict != nil {
+			return evicted, errEvict
+		}
+		if idle {
+			evicted = append(evicted, profile)
+		}
+	}
+
+	return evicted, nil
+}
+
+// evictIfIdle deletes the cluster if it has been idle for longer than idleTTL, the lock must be held by the caller.
+// The lock file is removed while it's held, processes waiting for it notice it's stale (see tryLockFile). Returns
+// whether the cluster was idle
+func (p *Pool) evictIfIdle(ctx context.Context, profile, statePath string, idleTTL time.Duration, deleteFn evictFunc) (bool, error) {
+	state, err := readPoolState(statePath)
+	if err != nil {
+		return false, err
+	}
+
+	if time.Since(state.LastUsed) < idleTTL {
+		return false, nil
+	}
+
+	deleted, err := deleteFn(ctx, profile)
+	if err != nil {
+		return false, fmt.Errorf("error evicting cluster %s: %w", profile, err)
+	}
+	if !deleted {
+		return true, nil
+	}
+
+	for _, path := range []string{statePath, p.path(profile, lockExtension)} {
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return false, fmt.Errorf("error removing %s of cluster %s: %w", filepath.Base(path), profile, err)
+		}
+	}
+
+	return true, nil
+}
+
+// deleteCluster deletes the cluster of the profile
+func (p *Pool) deleteCluster(ctx context.Context, profile string) (bool, error) {
+	if err := p.minikube(profile).Delete(ctx); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// lease prepares the cluster of the profile for the caller, the lock must be held by the caller
+func (p *Pool) lease(ctx context.Context, profile string, spec ClusterSpec, lock *os.File) (*Lease, error) {
+	mk := p.minikube(profile)
+	state := p.path(profile, stateExtension)
+
+	cli, reused, err := reuseCluster(ctx, mk)
+	if err != nil {
+		return nil, err
+	}
+
+	if !reused {
+		// record the cluster before creating it, so that it's evicted once idle even if the process dies during the
+		// creation
+		if err = writePoolState(state, poolState{Profile: profile, LastUsed: time.Now()}); err != nil {
+			return nil, err
+		}
+
+		// the cluster is either missing, broken or different from the spec, start from scratch
+		if err = mk.Delete(ctx); err != nil {
+			return nil, fmt.Errorf("error deleting cluster %s: %w", profile, err)
+		}
+
+		if cli, err = mk.CreateWithSpec(ctx, spec); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = resetNamespaces(ctx, cli, p.opts.Namespaces); err != nil {
+		return nil, err
+	}
+
+	if err = writePoolState(state, poolState{Profile: profile, LastUsed: time.Now()}); err != nil {
+		return nil, err
+	}
+
+	return &Lease{Minikube: mk, Client: cli, lock: lock, state: state}, nil
+}
+
+// reuseCluster returns the client of the cluster if it's healthy and matches the spec it was created with
+func reuseCluster(ctx context.Context, mk *Minikube) (client.Client, bool, error) {
+	status, err := mk.Status(ctx)
+	// the profile does not exist or minikube can't report its status, it must be recreated
+	if err != nil || !status.Healthy() || len(status.Drift) > 0 {
+		return nil, false, nil //nolint:nilerr // status errors mean that the cluster can't be reused
+	}
+
+	cli, err := mk.client()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// healthy clusters become ready quickly, don't spend the whole context waiting for a broken one
+	readyCtx, cancel := context.WithTimeout(ctx, PoolReuseTimeout)
+	defer cancel()
+
+	if err = cli.WaitForReadiness(readyCtx, mk.readiness); err != nil {
+		return nil, false, nil //nolint:nilerr // clusters that are not ready are recreated
+	}
+
+	return cli, true, nil
+}
+
+// minikube returns the orchestrator of the profile, bound to the runner of the pool
+func (p *Pool) minikube(profile string) *Minikube {
+	return NewMinikubeWithProfile(p.opts.Stdout, p.opts.Stderr, profile).WithRunner(p.opts.Runner)
+}
+
+// path returns the path of a file of the pool that belongs to the given profile
+func (p *Pool) path(profile, extension string) string {
+	return filepath.Join(p.opts.Dir, fmt.Sprintf("%s.%s", profile, extension))
+}
+
+// specHash returns a short hash that identifies the spec, clusters with the same hash are interchangeable
+func specHash(spec ClusterSpec) (string, error) {
+	content, err := yaml.Marshal(spec)
+	if err != nil {
+		return "", fmt.Errorf("error serializing cluster spec: %w", err)
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])[:specHashLength], nil
+}
+
+// resetNamespaces deletes the namespaces, waits until they are gone and creates them again
+func resetNamespaces(ctx context.Context, cli client.Client, namespaces []string) error {
+	nsClient := cli.ClientSet().CoreV1().Namespaces()
+
+	for _, ns := range namespaces {
+		err := nsClient.Delete(ctx, ns, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("error deleting namespace %s: %w", ns, err)
+		}
+
+		// namespaces are deleted asynchronously, wait until all the resources have been finalized
+		for !apierrors.IsNotFound(err) {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("namespace %s not deleted: %w", ns, ctx.Err())
+			case <-time.After(client.ReadinessPollInterval):
+			}
+
+			_, err = nsClient.Get(ctx, ns, metav1.GetOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("error getting namespace %s: %w", ns, err)
+			}
+		}
+
+		_, err = nsClient.Create(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("error creating namespace %s: %w", ns, err)
+		}
+	}
+
+	return nil
+}
+
+func readPoolState(path string) (poolState, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return poolState{}, fmt.Errorf("error reading pool state %s: %w", path, err)
+	}
+
+	var state poolState
+	if err = json.Unmarshal(content, &state); err != nil {
+		return poolState{}, fmt.Errorf("error parsing pool state %s: %w", path, err)
+	}
+
+	return state, nil
+}
+
+func writePoolState(path string, state poolState) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error serializing pool state: %w", err)
+	}
+
+	if err = os.WriteFile(path, content, 0o600); err != nil {
+		return fmt.Errorf("error writing pool state %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+//go:build linux || darwin
+
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecHash(t *testing.T) {
+	spec := ClusterSpec{KubernetesVersion: "1.30.0", Nodes: 1, CPUs: 2, Memory: 2048}
+
+	hash, err := specHash(spec)
+	require.NoError(t, err)
+	require.Len(t, hash, specHashLength)
+
+	same, err := specHash(spec)
+	require.NoError(t, err)
+	require.Equal(t, hash, same)
+
+	spec.Nodes = 2
+	different, err := specHash(spec)
+	require.NoError(t, err)
+	require.NotEqual(t, hash, different)
+}
+
+func TestTryLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.lock")
+
+	lock, acquired, err := tryLockFile(path)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	_, acquired, err = tryLockFile(path)
+	require.NoError(t, err)
+	require.False(t, acquired)
+
+	require.NoError(t, unlockFile(lock))
+
+	lock, acquired, err = tryLockFile(path)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	require.NoError(t, unlockFile(lock))
+}
+
+func TestPoolRecordsClusterBeforeCreation(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	// the status of the missing cluster can't be retrieved and the creation fails, as if the process died meanwhile
+	runner := runnerFunc(func(_ context.Context, cmd Command) error {
+		if cmd.Args[0] == "delete" {
+			return nil
+		}
+		return errors.New("exit status 80")
+	})
+	pool := NewPool(PoolOptions{Dir: t.TempDir(), Runner: runner})
+
+	spec := ClusterSpec{KubernetesVersion: "1.30.0", Nodes: 1, CPUs: 2, Memory: 2048}
+	_, err := pool.Acquire(context.Background(), spec)
+	require.Error(t, err)
+
+	// the state is left behind so that the cluster is evicted once idle
+	hash, err := specHash(spec)
+	require.NoError(t, err)
+	require.FileExists(t, pool.path(fmt.Sprintf("%s-%s-0", PoolProfilePrefix, hash), stateExtension))
+}
+
+func TestPoolEvictSkipsRecentClusters(t *testing.T) {
+	pool := NewPool(PoolOptions{Dir: t.TempDir(), IdleTTL: time.Hour})
+
+	state := pool.path("minikube-testing-pool-recent-0", stateExtension)
+	require.NoError(t, writePoolState(state, poolState{Profile: "minikube-testing-pool-recent-0", LastUsed: time.Now()}))
+
+	// the cluster has been used recently, minikube must not be invoked
+	require.NoError(t, pool.Evict(context.Background()))
+	require.FileExists(t, state)
+}
+
+func TestPoolEvictRemovesFiles(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	deleted := []string{}
+	runner := runnerFunc(func(_ context.Context, cmd Command) error {
+		deleted = append(deleted, cmd.Args[1])
+		return nil
+	})
+	pool := NewPool(PoolOptions{Dir: t.TempDir(), IdleTTL: time.Hour, Runner: runner})
+
+	for profile, lastUsed := range map[string]time.Time{
+		"minikube-testing-pool-idle-0":   time.Now().Add(-2 * time.Hour),
+		"minikube-testing-pool-recent-0": time.Now(),
+	} {
+		require.NoError(t, writePoolState(pool.path(profile, stateExtension), poolState{Profile: profile, LastUsed: lastUsed}))
+		lock, acquired, err := tryLockFile(pool.path(profile, lockExtension))
+		require.NoError(t, err)
+		require.True(t, acquired)
+		require.NoError(t, unlockFile(lock))
+	}
+
+	// only the idle cluster is evicted, its files are gone
+	require.NoError(t, pool.Evict(context.Background()))
+	require.Equal(t, []string{"--profile=minikube-testing-pool-idle-0"}, deleted)
+	require.NoFileExists(t, pool.path("minikube-testing-pool-idle-0", stateExtension))
+	require.NoFileExists(t, pool.path("minikube-testing-pool-idle-0", lockExtension))
+	require.FileExists(t, pool.path("minikube-testing-pool-recent-0", stateExtension))
+
+	// clusters leased are skipped even when evicting all of them
+	lock, acquired, err := tryLockFile(pool.path("minikube-testing-pool-recent-0", lockExtension))
+	require.NoError(t, err)
+	require.True(t, acquired)
+	require.NoError(t, pool.EvictAll(context.Background()))
+	require.FileExists(t, pool.path("minikube-testing-pool-recent-0", stateExtension))
+	require.NoError(t, unlockFile(lock))
+
+	require.NoError(t, pool.EvictAll(context.Background()))
+	require.Equal(t, []string{"--profile=minikube-testing-pool-idle-0", "--profile=minikube-testing-pool-recent-0"}, deleted)
+	require.NoFileExists(t, pool.path("minikube-testing-pool-recent-0", lockExtension))
+}
+
+func TestReapEvictsPool(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	idle := PoolProfilePrefix + "-0123456789ab-0"
+	pool := NewPool(PoolOptions{Dir: t.TempDir()})
+	require.NoError(t, writePoolState(pool.path(idle, stateExtension), poolState{Profile: idle, LastUsed: time.Now().Add(-3 * time.Hour)}))
+	require.NoError(t, recordImage(idle, "app:1.0"))
+
+	// pool profiles don't carry the creation time, they are evicted through their state instead
+	profiles := fmt.Sprintf(`{"valid": [{"Name": %q}]}`, idle)
+	replayer := NewReplayer([]CommandRecord{
+		{Name: "minikube", Args: []string{"profile", "list", "--output=json"}, Stdout: profiles},
+		{Name: "minikube", Args: []string{"profile", "list", "--output=json"}, Stdout: profiles},
+		{Name: "minikube", Args: []string{"delete", fmt.Sprintf("--profile=%s", idle)}},
+	})
+	images := &fakeImageRemover{}
+	opts := ReapOptions{Backends: []Backend{BackendMinikube}, PoolDir: pool.opts.Dir, Runner: replayer, Images: images}
+	want := []ReapedCluster{{Backend: BackendMinikube, Name: idle, Images: []string{"app:1.0"}}}
+
+	opts.DryRun = true
+	reaped, err := Reap(context.Background(), time.Hour, opts)
+	require.NoError(t, err)
+	require.Equal(t, want, reaped)
+	require.FileExists(t, pool.path(idle, stateExtension))
+
+	opts.DryRun = false
+	reaped, err = Reap(context.Background(), time.Hour, opts)
+	require.NoError(t, err)
+	require.Equal(t, want, reaped)
+	require.Empty(t, replayer.Remaining())
+	require.Equal(t, []string{"app:1.0"}, images.removed)
+	require.NoFileExists(t, pool.path(idle, stateExtension))
+	require.NoFileExists(t, pool.path(idle, lockExtension))
+}
+
+func TestTryLockFileRemoved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.lock")
+
+	// the holder removes the lock file, a process that opened it before must not keep the stale lock
+	stale, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	require.NoError(t, err)
+	defer stale.Close()
+	require.NoError(t, os.Remove(path))
+	require.False(t, sameFile(stale, path))
+
+	lock, acquired, err := tryLockFile(path)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	require.True(t, sameFile(lock, path))
+	require.NoError(t, unlockFile(lock))
+}
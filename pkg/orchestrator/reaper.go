@@ -0,0 +1,309 @@
+package orchestrator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yago-123/minikube-testing/pkg/runtime"
+)
+
+const (
+	// ProfilePrefix is prepended to the names generated by NewMinikube and NewKind, so that they can be told apart
+	// from the clusters created by hand
+	ProfilePrefix = "minikube-testing"
+	// K3dNamePrefix is prepended to the names generated by NewK3d, it's shorter than ProfilePrefix because k3d limits
+	// cluster names to 32 characters
+	K3dNamePrefix = "mk-testing"
+)
+
+// ImageRemover removes images from the local docker daemon, runtime.Docker implements it
+type ImageRemover interface {
+	RemoveImage(ctx context.Context, image, tag string) error
+}
+
+// ReapOptions contains the settings used to look for leaked clusters
+type ReapOptions struct {
+	// DryRun reports the clusters that would be deleted without deleting them
+	DryRun bool
+	// Backends are the backends whose clusters are reaped, defaults to all of them. Backends whose binary is not
+	// installed are skipped
+	Backends []Backend
+	// PoolDir is the directory of the pool whose idle clusters are evicted, defaults to the directory of NewPool
+	PoolDir string
+	// Runner executes the binaries of the backends, defaults to ExecRunner
+	Runner CommandRunner
+	// Images removes the host images loaded into the clusters reaped, defaults to the docker daemon of the environment
+	Images ImageRemover
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ReapedCluster identifies a cluster deleted by Reap together with the host images loaded into it
+type ReapedCluster struct {
+	Backend Backend
+	Name    string
+	Images  []string
+}
+
+// k3dCluster is the subset of the output of k3d cluster list used to reap clusters
+type k3dCluster struct {
+	Name string `json:"name"`
+}
+
+// generateName generates a random cluster name that contains the creation time, in the form
+// <prefix>-<unix timestamp>-<random suffix>
+func generateName(prefix string) string {
+	return fmt.Sprintf("%s-%d-%s", prefix, time.Now().Unix(), strings.Split(uuid.NewString(), "-")[0])
+}
+
+// nameCreation extracts the creation time from a name generated by generateName with the given prefix, returns false
+// for any other name
+func nameCreation(prefix, name string) (time.Time, bool) {
+	rest, found := strings.CutPrefix(name, fmt.Sprintf("%s-", prefix))
+	if !found {
+		return time.Time{}, false
+	}
+
+	timestamp, suffix, found := strings.Cut(rest, "-")
+	if !found || suffix == "" || strings.Contains(suffix, "-") {
+		return time.Time{}, false
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(seconds, 0), true
+}
+
+// namePrefix returns the prefix of the names generated for the clusters of the backend
+func namePrefix(backend Backend) string {
+	if backend == BackendK3d {
+		return K3dNamePrefix
+	}
+
+	return ProfilePrefix
+}
+
+// Reap deletes the clusters generated by NewMinikube, NewKind and NewK3d that were created more than olderThan ago,
+// usually leaked by test processes that died before deleting them, together with the host images loaded into them.
+// The pool clusters that have been idle for longer than olderThan and are not leased are evicted as well. Clusters
+// created by hand or with an explicit name are never touched. Images are removed from the docker daemon even if
+// other clusters use them, they are rebuilt or pulled by the next run. Returns the clusters deleted, or the ones that
+// would be deleted in dry run mode
+func Reap(ctx context.Context, olderThan time.Duration, opts ReapOptions) ([]ReapedCluster, error) {
+	if len(opts.Backends) == 0 {
+		opts.Backends = []Backend{BackendMinikube, BackendKind, BackendK3d}
+	}
+	if opts.Runner == nil {
+		opts.Runner = ExecRunner{}
+	}
+	if opts.Images == nil {
+		dc, err := runtime.NewDockerController()
+		if err != nil {
+			return []ReapedCluster{}, fmt.Errorf("unable to create docker controller: %w", err)
+		}
+		opts.Images = dc
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = io.Discard
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = io.Discard
+	}
+
+	reaped, errs := []ReapedCluster{}, []error{}
+	for _, backend := range opts.Backends {
+		names, err := listClusters(ctx, backend, opts)
+		// the backend is not installed, so it can't have leaked any cluster
+		if errors.Is(err, exec.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, name := range names {
+			created, generated := nameCreation(namePrefix(backend), name)
+			if !generated || time.Since(created) < olderThan {
+				continue
+			}
+
+			cluster, errReap := reapCluster(ctx, backend, name, opts)
+			if errReap != nil {
+				errs = append(errs, errReap)
+			}
+			if cluster != nil {
+				reaped = append(reaped, *cluster)
+			}
+		}
+
+		if backend == BackendMinikube {
+			evicted, errEvict := reapPool(ctx, olderThan, opts)
+			reaped = append(reaped, evicted...)
+			if errEvict != nil {
+				errs = append(errs, errEvict)
+			}
+		}
+	}
+
+	return reaped, errors.Join(errs...)
+}
+
+// reapCluster deletes the cluster and then the host images recorded for it, the images must be read before the
+// cluster is deleted. Returns nil if the cluster could not be deleted
+func reapCluster(ctx context.Context, backend Backend, name string, opts ReapOptions) (*ReapedCluster, error) {
+	images, err := recordedImages(name)
+	if err != nil {
+		return nil, fmt.Errorf("error reaping %s cluster %s: %w", backend, name, err)
+	}
+
+	cluster := &ReapedCluster{Backend: backend, Name: name, Images: images}
+	if opts.DryRun {
+		return cluster, nil
+	}
+
+	if err = reapOrchestrator(backend, name, opts).Delete(ctx); err != nil {
+		return nil, fmt.Errorf("error reaping %s cluster %s: %w", backend, name, err)
+	}
+
+	errs := []error{}
+	for _, ref := range images {
+		image, tag := splitImageRef(ref)
+		if errRemove := opts.Images.RemoveImage(ctx, image, tag); errRemove != nil {
+			errs = append(errs, fmt.Errorf("error removing image %s of %s cluster %s: %w", ref, backend, name, errRemove))
+		}
+	}
+
+	return cluster, errors.Join(errs...)
+}
+
+// reapPool evicts the pool clusters idle for longer than olderThan, the ones leased at the moment are skipped
+func reapPool(ctx context.Context, olderThan time.Duration, opts ReapOptions) ([]ReapedCluster, error) {
+	pool := NewPool(PoolOptions{Dir: opts.PoolDir, Runner: opts.Runner, Stdout: opts.Stdout, Stderr: opts.Stderr})
+
+	reaped, errs := []ReapedCluster{}, []error{}
+	_, err := pool.evict(ctx, olderThan, func(ctx context.Context, profile string) (bool, error) {
+		cluster, errReap := reapCluster(ctx, BackendMinikube, profile, opts)
+		if cluster == nil {
+			return false, errReap
+		}
+
+		// images that could not be removed don't prevent the eviction, the cluster is gone already
+		reaped = append(reaped, *cluster)
+		if errReap != nil {
+			errs = append(errs, errReap)
+		}
+		return !opts.DryRun, nil
+	})
+
+	return reaped, errors.Join(append(errs, err)...)
+}
+
+// listClusters returns the names of all the clusters of the backend
+func listClusters(ctx context.Context, backend Backend, opts ReapOptions) ([]string, error) {
+	switch backend {
+	case BackendMinikube:
+		// profile list is not bound to any profile
+		profiles, err := NewMinikubeWithProfile(opts.Stdout, opts.Stderr, "").WithRunner(opts.Runner).Profiles(ctx)
+		if err != nil {
+			return []string{}, err
+		}
+
+		names := []string{}
+		for _, profile := range profiles {
+			names = append(names, profile.Name)
+		}
+		return names, nil
+	case BackendKind:
+		out, err := runOutput(ctx, kindBinary, opts, "get", "clusters")
+		if err != nil {
+			return []string{}, fmt.Errorf("failed to list kind clusters: %w", err)
+		}
+		return parseKindClusters(out)
+	case BackendK3d:
+		out, err := runOutput(ctx, k3dBinary, opts, "cluster", "list", "--output=json")
+		if err != nil {
+			return []string{}, fmt.Errorf("failed to list k3d clusters: %w", err)
+		}
+		return parseK3dClusters(out)
+	default:
+		return []string{}, fmt.Errorf("unsupported orchestrator backend %q", backend)
+	}
+}
+
+// reapOrchestrator returns the orchestrator bound to the cluster of the backend, the backend must be supported
+func reapOrchestrator(backend Backend, name string, opts ReapOptions) Orchestrator {
+	switch backend {
+	case BackendKind:
+		return NewKindWithName(opts.Stdout, opts.Stderr, name).WithRunner(opts.Runner)
+	case BackendK3d:
+		return NewK3dWithOptions(opts.Stdout, opts.Stderr, name, K3dOptions{}).WithRunner(opts.Runner)
+	default:
+		return NewMinikubeWithProfile(opts.Stdout, opts.Stderr, name).WithRunner(opts.Runner)
+	}
+}
+
+// runOutput executes the binary with the given arguments and returns its standard output
+func runOutput(ctx context.Context, binary string, opts ReapOptions, args ...string) ([]byte, error) {
+	out := new(bytes.Buffer)
+	err := opts.Runner.Run(ctx, Command{
+		Name:   binary,
+		Args:   args,
+		Stdout: out,
+		Stderr: opts.Stderr,
+	})
+
+	return out.Bytes(), err
+}
+
+// parseKindClusters parses the output of kind get clusters, one cluster per line. kind reports the absence of
+// clusters through stderr, so the output is empty in that case
+func parseKindClusters(out []byte) ([]string, error) {
+	names := []string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return []string{}, fmt.Errorf("error reading kind clusters: %w", err)
+	}
+
+	return names, nil
+}
+
+// parseK3dClusters parses the output of k3d cluster list --output=json
+func parseK3dClusters(out []byte) ([]string, error) {
+	if len(bytes.TrimSpace(out)) == 0 {
+		return []string{}, nil
+	}
+
+	clusters := []k3dCluster{}
+	if err := json.Unmarshal(out, &clusters); err != nil {
+		return []string{}, fmt.Errorf("error parsing k3d clusters: %w", err)
+	}
+
+	names := []string{}
+	for _, cluster := range clusters {
+		names = append(names, cluster.Name)
+	}
+
+	return names, nil
+}
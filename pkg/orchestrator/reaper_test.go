@@ -0,0 +1,136 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNameCreation(t *testing.T) {
+	for _, prefix := range []string{ProfilePrefix, K3dNamePrefix} {
+		name := generateName(prefix)
+		require.True(t, strings.HasPrefix(name, prefix))
+
+		created, generated := nameCreation(prefix, name)
+		require.True(t, generated)
+		require.WithinDuration(t, time.Now(), created, 2*time.Second)
+	}
+
+	// k3d limits cluster names to 32 characters
+	require.LessOrEqual(t, len(generateName(K3dNamePrefix)), 32)
+
+	for _, name := range []string{"minikube", "minikube-testing", "minikube-testing-abc-1234", PoolProfilePrefix + "-0123456789ab-0"} {
+		_, generated := nameCreation(ProfilePrefix, name)
+		require.False(t, generated, name)
+	}
+}
+
+func TestReap(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	old := fmt.Sprintf("%s-%d-0a1b2c3d", ProfilePrefix, time.Now().Add(-3*time.Hour).Unix())
+	recent := fmt.Sprintf("%s-%d-4e5f6a7b", ProfilePrefix, time.Now().Unix())
+	profiles := fmt.Sprintf(`{
+		"invalid": [],
+		"valid": [
+			{"Name": "minikube", "Status": "Running", "Config": {}},
+			{"Name": %q, "Status": "Running", "Config": {}},
+			{"Name": %q, "Status": "Running", "Config": {}}
+		]
+	}`, old, recent)
+
+	require.NoError(t, recordImage(old, "app:1.0"))
+	require.NoError(t, recordImage(old, "localhost:5000/api"))
+
+	oldKind := fmt.Sprintf("%s-%d-1a2b3c4d", ProfilePrefix, time.Now().Add(-3*time.Hour).Unix())
+	kindClusters := fmt.Sprintf("kind\n%s\n", oldKind)
+
+	oldK3d := fmt.Sprintf("%s-%d-5e6f7a8b", K3dNamePrefix, time.Now().Add(-3*time.Hour).Unix())
+	k3dClusters := fmt.Sprintf(`[{"name": "k3s-default"}, {"name": %q}]`, oldK3d)
+
+	replayer := NewReplayer([]CommandRecord{
+		{Name: "minikube", Args: []string{"profile", "list", "--output=json"}, Stdout: profiles},
+		{Name: "kind", Args: []string{"get", "clusters"}, Stdout: kindClusters},
+		{Name: "k3d", Args: []string{"cluster", "list", "--output=json"}, Stdout: k3dClusters},
+		{Name: "minikube", Args: []string{"profile", "list", "--output=json"}, Stdout: profiles},
+		{Name: "minikube", Args: []string{"delete", fmt.Sprintf("--profile=%s", old)}},
+		{Name: "kind", Args: []string{"get", "clusters"}, Stdout: kindClusters},
+		{
+			Name: "kind",
+			Args: []string{"delete", "cluster", fmt.Sprintf("--name=%s", oldKind), fmt.Sprintf("--kubeconfig=%s", clusterFilePath(oldKind, "kubeconfig"))},
+		},
+		{Name: "k3d", Args: []string{"cluster", "list", "--output=json"}, Stdout: k3dClusters},
+		{Name: "k3d", Args: []string{"cluster", "delete", oldK3d}},
+	})
+	want := []ReapedCluster{
+		{Backend: BackendMinikube, Name: old, Images: []string{"app:1.0", "localhost:5000/api"}},
+		{Backend: BackendKind, Name: oldKind, Images: []string{}},
+		{Backend: BackendK3d, Name: oldK3d, Images: []string{}},
+	}
+	images := &fakeImageRemover{}
+	opts := ReapOptions{Runner: replayer, Images: images, PoolDir: t.TempDir()}
+
+	opts.DryRun = true
+	reaped, err := Reap(context.Background(), time.Hour, opts)
+	require.NoError(t, err)
+	require.Equal(t, want, reaped)
+	require.Empty(t, images.removed)
+
+	opts.DryRun = false
+	reaped, err = Reap(context.Background(), time.Hour, opts)
+	require.NoError(t, err)
+	require.Equal(t, want, reaped)
+	require.Empty(t, replayer.Remaining())
+	require.Equal(t, []string{"app:1.0", "localhost:5000/api:latest"}, images.removed)
+	require.NoFileExists(t, clusterFilePath(old, imagesRecordName))
+}
+
+func TestReapKeepsImagesRecordOnFailure(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	old := fmt.Sprintf("%s-%d-0a1b2c3d", ProfilePrefix, time.Now().Add(-3*time.Hour).Unix())
+	require.NoError(t, recordImage(old, "app:1.0"))
+
+	replayer := NewReplayer([]CommandRecord{
+		{Name: "minikube", Args: []string{"profile", "list", "--output=json"}, Stdout: fmt.Sprintf(`{"valid": [{"Name": %q}]}`, old)},
+		{Name: "minikube", Args: []string{"delete", fmt.Sprintf("--profile=%s", old)}, ExitCode: 1},
+	})
+	images := &fakeImageRemover{}
+
+	// the images of a cluster that could not be deleted are kept, the next run retries both
+	reaped, err := Reap(context.Background(), time.Hour, ReapOptions{
+		Backends: []Backend{BackendMinikube},
+		Runner:   replayer,
+		Images:   images,
+		PoolDir:  t.TempDir(),
+	})
+	require.Error(t, err)
+	require.Empty(t, reaped)
+	require.Empty(t, images.removed)
+	require.FileExists(t, clusterFilePath(old, imagesRecordName))
+}
+
+func TestReapSkipsMissingBackends(t *testing.T) {
+	runner := runnerFunc(func(_ context.Context, cmd Command) error {
+		return &exec.Error{Name: cmd.Name, Err: exec.ErrNotFound}
+	})
+
+	reaped, err := Reap(context.Background(), time.Hour, ReapOptions{Runner: runner, Images: &fakeImageRemover{}})
+	require.NoError(t, err)
+	require.Empty(t, reaped)
+}
+
+// fakeImageRemover records the images removed instead of talking to the docker daemon
+type fakeImageRemover struct {
+	removed []string
+}
+
+func (f *fakeImageRemover) RemoveImage(_ context.Context, image, tag string) error {
+	f.removed = append(f.removed, fmt.Sprintf("%s:%s", image, tag))
+	return nil
+}
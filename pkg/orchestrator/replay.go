@@ -0,0 +1,215 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrCommandNotRecorded is returned by the Replayer when it's asked to run a command that is not in the fixture
+var ErrCommandNotRecorded = errors.New("command not recorded")
+
+// CommandRecord contains the invocation of a command together with its outcome, the environment is not recorded
+// because it usually contains paths that change between runs
+type CommandRecord struct {
+	Name     string   `json:"name"`
+	Args     []string `json:"args"`
+	Stdout   string   `json:"stdout"`
+	Stderr   string   `json:"stderr"`
+	ExitCode int      `json:"exitCode"`
+	// Error is set when the command failed without an exit code (e.g. binary not found or context cancelled)
+	Error string `json:"error,omitempty"`
+}
+
+// ExitCodeError is returned by the Replayer for the recorded commands that exited with a non-zero code
+type ExitCodeError struct {
+	Name string
+	Code int
+}
+
+func (e *ExitCodeError) Error() string {
+	return fmt.Sprintf("%s exited with code %d", e.Name, e.Code)
+}
+
+// make sure that Recorder and Replayer implement the CommandRunner interface
+var (
+	_ CommandRunner = (*Recorder)(nil)
+	_ CommandRunner = (*Replayer)(nil)
+)
+
+// Recorder wraps a CommandRunner and captures every command executed through it, the records can be saved as a
+// fixture and served back with a Replayer
+type Recorder struct {
+	runner CommandRunner
+
+	mu      sync.Mutex
+	records []CommandRecord
+}
+
+func NewRecorder(runner CommandRunner) *Recorder {
+	return &Recorder{
+		runner:  runner,
+		records: []CommandRecord{},
+	}
+}
+
+// Run executes the command with the wrapped runner and records the output and the exit code
+func (r *Recorder) Run(ctx context.Context, cmd Command) error {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+
+	recorded := cmd
+	recorded.Stdout = io.MultiWriter(nonNilWriter(cmd.Stdout), stdout)
+	recorded.Stderr = io.MultiWriter(nonNilWriter(cmd.Stderr), stderr)
+
+	err := r.runner.Run(ctx, recorded)
+
+	record := CommandRecord{
+		Name:   cmd.Name,
+		Args:   slices.Clone(cmd.Args),
+		Stdout: stdout.String(),
+		Stderr: stderr.String(),
+	}
+
+	var exitErr *exec.ExitError
+	var codeErr *ExitCodeError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		record.ExitCode = exitErr.ExitCode()
+	case errors.As(err, &codeErr):
+		record.ExitCode = codeErr.Code
+	default:
+		record.ExitCode = -1
+		record.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	r.records = append(r.records, record)
+	r.mu.Unlock()
+
+	return err
+}
+
+// Records returns a copy of the commands recorded so far
+func (r *Recorder) Records() []CommandRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.records)
+}
+
+// Save writes the commands recorded so far into a fixture file that can be loaded with LoadReplayer
+func (r *Recorder) Save(path string) error {
+	content, err := json.MarshalIndent(r.Records(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error serializing command records: %w", err)
+	}
+
+	if err = os.WriteFile(path, content, 0o600); err != nil {
+		return fmt.Errorf("error writing command records %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Replayer serves recorded commands back instead of executing them. Each record is served once, commands are
+// matched by name and arguments in the order they were recorded, so the same command can return different outputs
+// in consecutive calls
+type Replayer struct {
+	mu      sync.Mutex
+	records []CommandRecord
+	used    []bool
+}
+
+func NewReplayer(records []CommandRecord) *Replayer {
+	return &Replayer{
+		records: records,
+		used:    make([]bool, len(records)),
+	}
+}
+
+// LoadReplayer creates a Replayer from a fixture file written by Recorder.Save
+func LoadReplayer(path string) (*Replayer, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading command records %s: %w", path, err)
+	}
+
+	records := []CommandRecord{}
+	if err = json.Unmarshal(content, &records); err != nil {
+		return nil, fmt.Errorf("error parsing command records %s: %w", path, err)
+	}
+
+	return NewReplayer(records), nil
+}
+
+// Run writes the output of the first unused record that matches the command and returns its outcome
+func (r *Replayer) Run(_ context.Context, cmd Command) error {
+	record, found := r.next(cmd)
+	if !found {
+		return fmt.Errorf("%w: %s %s", ErrCommandNotRecorded, cmd.Name, strings.Join(cmd.Args, " "))
+	}
+
+	if _, err := io.WriteString(nonNilWriter(cmd.Stdout), record.Stdout); err != nil {
+		return fmt.Errorf("error writing recorded stdout: %w", err)
+	}
+	if _, err := io.WriteString(nonNilWriter(cmd.Stderr), record.Stderr); err != nil {
+		return fmt.Errorf("error writing recorded stderr: %w", err)
+	}
+
+	if record.Error != "" {
+		return errors.New(record.Error)
+	}
+	if record.ExitCode != 0 {
+		return &ExitCodeError{Name: record.Name, Code: record.ExitCode}
+	}
+
+	return nil
+}
+
+// Remaining returns the records that have not been served yet, useful to verify that all the expected commands ran
+func (r *Replayer) Remaining() []CommandRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	remaining := []CommandRecord{}
+	for i, record := range r.records {
+		if !r.used[i] {
+			remaining = append(remaining, record)
+		}
+	}
+
+	return remaining
+}
+
+// next marks the first unused record that matches the command as used and returns it
+func (r *Replayer) next(cmd Command) (CommandRecord, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, record := range r.records {
+		if !r.used[i] && record.Name == cmd.Name && slices.Equal(record.Args, cmd.Args) {
+			r.used[i] = true
+			return record, true
+		}
+	}
+
+	return CommandRecord{}, false
+}
+
+// nonNilWriter returns io.Discard for nil writers, exec accepts nil writers but io.MultiWriter does not
+func nonNilWriter(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+
+	return w
+}
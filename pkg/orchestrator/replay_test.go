@@ -0,0 +1,58 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayerMinikube(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	replayer, err := LoadReplayer(filepath.Join("testdata", "minikube_status.json"))
+	require.NoError(t, err)
+
+	stdout := new(bytes.Buffer)
+	mk := NewMinikubeWithProfile(stdout, io.Discard, "replay-profile").WithRunner(replayer)
+
+	status, err := mk.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, status.Nodes, 2)
+	require.False(t, status.Healthy())
+
+	require.NoError(t, mk.Delete(context.Background()))
+	require.Contains(t, stdout.String(), "Deleting")
+	require.Empty(t, replayer.Remaining())
+
+	// every record is served once
+	_, err = mk.Status(context.Background())
+	require.ErrorIs(t, err, ErrCommandNotRecorded)
+}
+
+func TestRecorderRoundTrip(t *testing.T) {
+	source := NewReplayer([]CommandRecord{
+		{Name: "kind", Args: []string{"get", "clusters"}, Stdout: "first\n"},
+		{Name: "kind", Args: []string{"get", "clusters"}, Stdout: "second\n", Stderr: "failed\n", ExitCode: 1},
+	})
+	recorder := NewRecorder(source)
+
+	stdout := new(bytes.Buffer)
+	cmd := Command{Name: "kind", Args: []string{"get", "clusters"}, Stdout: stdout}
+
+	require.NoError(t, recorder.Run(context.Background(), cmd))
+	var exitErr *ExitCodeError
+	require.ErrorAs(t, recorder.Run(context.Background(), cmd), &exitErr)
+	require.Equal(t, 1, exitErr.Code)
+	require.Equal(t, "first\nsecond\n", stdout.String())
+
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+	require.NoError(t, recorder.Save(fixture))
+
+	replayer, err := LoadReplayer(fixture)
+	require.NoError(t, err)
+	require.Equal(t, recorder.Records(), replayer.Remaining())
+}
@@ -0,0 +1,101 @@
+package orchestrator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// tunnelReadyMarker is printed by minikube tunnel once the routes to the cluster are in place
+const tunnelReadyMarker = "Status:"
+
+// TunnelHandle represents a minikube tunnel running in background, LoadBalancer services get an external IP while
+// it's running
+type TunnelHandle struct {
+	process *backgroundProcess
+}
+
+// Close stops the tunnel so that minikube removes the routes, returns the error of the process if it had already
+// exited on its own
+func (t *TunnelHandle) Close() error {
+	if err := t.process.stop(); err != nil {
+		return fmt.Errorf("tunnel failed: %w", err)
+	}
+
+	return nil
+}
+
+// Done returns a channel that is closed once the tunnel is gone
+func (t *TunnelHandle) Done() <-chan struct{} {
+	return t.process.exited()
+}
+
+// Tunnel runs minikube tunnel in background and blocks until the routes are in place. minikube supports a single
+// tunnel per profile, the tunnel is closed with TunnelHandle.Close, when the context finishes or when the cluster is
+// deleted. Depending on the driver minikube requires root permissions to create the routes
+func (mc *Minikube) Tunnel(ctx context.Context) (*TunnelHandle, error) {
+	process, err := startBackground(ctx, mc.runner, Command{
+		Name:   minikubeBinary,
+		Args:   []string{"tunnel", "--cleanup", fmt.Sprintf("--profile=%s", mc.profile)},
+		Env:    mc.env(),
+		Stdout: mc.stdout,
+		Stderr: mc.stderr,
+	}, tunnelReadyMarker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start tunnel: %w", err)
+	}
+
+	mc.track(process)
+
+	return &TunnelHandle{process: process}, nil
+}
+
+// ServiceURL returns the URLs through which the service can be reached from the host, one for each port of the
+// service. Works for NodePort and LoadBalancer services. Drivers that need minikube to keep running to reach the
+// services (e.g. docker on macOS) are not supported, run a Tunnel instead
+func (mc *Minikube) ServiceURL(ctx context.Context, namespace, name string) ([]string, error) {
+	out, err := mc.output(
+		ctx,
+		"service",
+		name,
+		"--url",
+		fmt.Sprintf("--namespace=%s", namespace),
+		fmt.Sprintf("--profile=%s", mc.profile),
+	)
+	if err != nil {
+		return []string{}, fmt.Errorf("failed to retrieve URL of service %s/%s: %w", namespace, name, err)
+	}
+
+	urls, err := parseServiceURLs(out)
+	if err != nil {
+		return []string{}, fmt.Errorf("failed to retrieve URL of service %s/%s: %w", namespace, name, err)
+	}
+
+	return urls, nil
+}
+
+// parseServiceURLs extracts the URLs from the output of minikube service --url, ignoring any informative message
+func parseServiceURLs(out []byte) ([]string, error) {
+	urls := []string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.Contains(line, "://") {
+			urls = append(urls, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return []string{}, fmt.Errorf("error reading service URLs: %w", err)
+	}
+
+	if len(urls) == 0 {
+		return []string{}, errors.New("service does not expose any port")
+	}
+
+	return urls, nil
+}
@@ -0,0 +1,66 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceURL(t *testing.T) {
+	replayer := NewReplayer([]CommandRecord{
+		{
+			Name:   "minikube",
+			Args:   []string{"service", "api", "--url", "--namespace=apps", "--profile=my-profile"},
+			Stdout: "http://192.168.49.2:30080\nhttp://192.168.49.2:30443\n",
+		},
+		{
+			Name:   "minikube",
+			Args:   []string{"service", "headless", "--url", "--namespace=apps", "--profile=my-profile"},
+			Stdout: "|-----------|----------|-------------|--------------|\n* service apps/headless has no node port\n",
+		},
+	})
+	mk := NewMinikubeWithProfile(io.Discard, io.Discard, "my-profile").WithRunner(replayer)
+
+	urls, err := mk.ServiceURL(context.Background(), "apps", "api")
+	require.NoError(t, err)
+	require.Equal(t, []string{"http://192.168.49.2:30080", "http://192.168.49.2:30443"}, urls)
+
+	_, err = mk.ServiceURL(context.Background(), "apps", "headless")
+	require.Error(t, err)
+}
+
+func TestTunnel(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	// the runner is executed in a background goroutine, the arguments are sent back to be asserted in the test
+	args := make(chan []string, 1)
+	runner := runnerFunc(func(ctx context.Context, cmd Command) error {
+		if cmd.Args[0] != "tunnel" {
+			return nil
+		}
+
+		args <- cmd.Args
+		if _, err := io.WriteString(cmd.Stdout, "Status:\n\tmachine: my-profile\n\troute: 10.96.0.0/12 -> 192.168.49.2\n"); err != nil {
+			return err
+		}
+
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	mk := NewMinikubeWithProfile(io.Discard, io.Discard, "my-profile").WithRunner(runner)
+	tunnelArgs := []string{"tunnel", "--cleanup", "--profile=my-profile"}
+
+	tunnel, err := mk.Tunnel(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, tunnelArgs, <-args)
+	require.NoError(t, tunnel.Close())
+	<-tunnel.Done()
+
+	tunnel, err = mk.Tunnel(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, tunnelArgs, <-args)
+	require.NoError(t, mk.Delete(context.Background()))
+	<-tunnel.Done()
+}
@@ -0,0 +1,205 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// MinikubeMinCPUs and MinikubeMinMemory are the minimum resources accepted by minikube start
+	MinikubeMinCPUs   = 2
+	MinikubeMinMemory = 1800
+)
+
+//nolint:gochecknoglobals // read-only lists of values accepted by minikube start
+var (
+	supportedDrivers = []string{
+		"docker", "podman", "kvm2", "qemu2", "virtualbox", "vmware", "parallels", "hyperkit", "hyperv", "vfkit", "ssh", "none",
+	}
+	supportedContainerRuntimes = []string{"docker", "containerd", "cri-o"}
+	supportedCNIs              = []string{"auto", "bridge", "calico", "cilium", "flannel", "kindnet", "false"}
+	supportedComponents        = []string{
+		"kubelet", "kubeadm", "apiserver", "controller-manager", "scheduler", "etcd", "kube-proxy",
+	}
+)
+
+// MountSpec represents a host directory mounted into the nodes during start
+type MountSpec struct {
+	HostPath string `json:"hostPath"`
+	NodePath string `json:"nodePath"`
+}
+
+// ClusterSpec contains the settings of a minikube cluster, memory and disk size are expressed in MB
+type ClusterSpec struct {
+	KubernetesVersion string `json:"kubernetesVersion"`
+	Nodes             uint   `json:"nodes"`
+	CPUs              uint   `json:"cpus"`
+	Memory            uint   `json:"memory"`
+	DiskSize          uint   `json:"diskSize,omitempty"`
+
+	Driver           string `json:"driver,omitempty"`
+	ContainerRuntime string `json:"containerRuntime,omitempty"`
+	// CNI can be any of the plugins supported by minikube or the path to a CNI manifest
+	CNI string `json:"cni,omitempty"`
+
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// ExtraConfig contains component settings in the form component.key=value (e.g. kubelet.max-pods=100)
+	ExtraConfig []string `json:"extraConfig,omitempty"`
+	Addons      []string `json:"addons,omitempty"`
+	// Ports are exposed from the node container in the form [hostPort:]containerPort, docker and podman only
+	Ports              []string   `json:"ports,omitempty"`
+	Mount              *MountSpec `json:"mount,omitempty"`
+	InsecureRegistries []string   `json:"insecureRegistries,omitempty"`
+}
+
+// LoadClusterSpec reads and validates the cluster spec from a YAML file
+func LoadClusterSpec(path string) (ClusterSpec, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ClusterSpec{}, fmt.Errorf("error reading cluster spec %s: %w", path, err)
+	}
+
+	return ParseClusterSpec(content)
+}
+
+// ParseClusterSpec parses and validates the cluster spec from YAML, unknown fields are rejected
+func ParseClusterSpec(content []byte) (ClusterSpec, error) {
+	var spec ClusterSpec
+	if err := yaml.UnmarshalStrict(content, &spec); err != nil {
+		return ClusterSpec{}, fmt.Errorf("error parsing cluster spec: %w", err)
+	}
+
+	if err := spec.Validate(); err != nil {
+		return ClusterSpec{}, err
+	}
+
+	return spec, nil
+}
+
+// Validate checks that the spec only contains values accepted by minikube, all the problems are reported together
+func (s ClusterSpec) Validate() error {
+	errs := []error{}
+
+	if s.KubernetesVersion == "" {
+		errs = append(errs, errors.New("kubernetesVersion is required"))
+	}
+	if s.Nodes == 0 {
+		errs = append(errs, errors.New("at least one node is required"))
+	}
+	if s.CPUs < MinikubeMinCPUs {
+		errs = append(errs, fmt.Errorf("at least %d cpus are required, got %d", MinikubeMinCPUs, s.CPUs))
+	}
+	if s.Memory < MinikubeMinMemory {
+		errs = append(errs, fmt.Errorf("at least %dMB of memory are required, got %d", MinikubeMinMemory, s.Memory))
+	}
+	if s.Driver != "" && !slices.Contains(supportedDrivers, s.Driver) {
+		errs = append(errs, fmt.Errorf("unsupported driver %q", s.Driver))
+	}
+	if s.ContainerRuntime != "" && !slices.Contains(supportedContainerRuntimes, s.ContainerRuntime) {
+		errs = append(errs, fmt.Errorf("unsupported container runtime %q", s.ContainerRuntime))
+	}
+	if s.CNI != "" && !slices.Contains(supportedCNIs, s.CNI) && !filepath.IsAbs(s.CNI) {
+		errs = append(errs, fmt.Errorf("unsupported cni %q, must be a known plugin or an absolute path", s.CNI))
+	}
+
+	for _, extra := range s.ExtraConfig {
+		component, _, found := strings.Cut(extra, ".")
+		if !found || !strings.Contains(extra, "=") || !slices.Contains(supportedComponents, component) {
+			errs = append(errs, fmt.Errorf("invalid extra config %q, expected component.key=value", extra))
+		}
+	}
+
+	for _, port := range s.Ports {
+		for _, p := range strings.Split(port, ":") {
+			if _, err := strconv.ParseUint(p, 10, 16); err != nil {
+				errs = append(errs, fmt.Errorf("invalid port %q, expected [hostPort:]containerPort", port))
+				break
+			}
+		}
+	}
+
+	if s.Mount != nil && (!filepath.IsAbs(s.Mount.HostPath) || !filepath.IsAbs(s.Mount.NodePath)) {
+		errs = append(errs, fmt.Errorf("mount paths must be absolute, got %s:%s", s.Mount.HostPath, s.Mount.NodePath))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid cluster spec: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// StartArgs translates the spec into the arguments of minikube start for the given profile
+func (s ClusterSpec) StartArgs(profile string) []string {
+	args := []string{
+		"start",
+		fmt.Sprintf("--kubernetes-version=%s", s.KubernetesVersion),
+		fmt.Sprintf("--nodes=%d", s.Nodes),
+		fmt.Sprintf("--cpus=%d", s.CPUs),
+		fmt.Sprintf("--memory=%d", s.Memory),
+	}
+
+	if s.DiskSize > 0 {
+		args = append(args, fmt.Sprintf("--disk-size=%dmb", s.DiskSize))
+	}
+	if s.Driver != "" {
+		args = append(args, fmt.Sprintf("--driver=%s", s.Driver))
+	}
+	if s.ContainerRuntime != "" {
+		args = append(args, fmt.Sprintf("--container-runtime=%s", s.ContainerRuntime))
+	}
+	if s.CNI != "" {
+		args = append(args, fmt.Sprintf("--cni=%s", s.CNI))
+	}
+
+	if len(s.FeatureGates) > 0 {
+		gates := []string{}
+		for gate, enabled := range s.FeatureGates {
+			gates = append(gates, fmt.Sprintf("%s=%t", gate, enabled))
+		}
+		// map iteration is random, sort to keep the arguments stable
+		sort.Strings(gates)
+		args = append(args, fmt.Sprintf("--feature-gates=%s", strings.Join(gates, ",")))
+	}
+
+	for _, extra := range s.ExtraConfig {
+		args = append(args, fmt.Sprintf("--extra-config=%s", extra))
+	}
+	for _, addon := range s.Addons {
+		args = append(args, fmt.Sprintf("--addons=%s", addon))
+	}
+	for _, port := range s.Ports {
+		args = append(args, fmt.Sprintf("--ports=%s", port))
+	}
+	if s.Mount != nil {
+		args = append(args, "--mount", fmt.Sprintf("--mount-string=%s:%s", s.Mount.HostPath, s.Mount.NodePath))
+	}
+	for _, registry := range s.InsecureRegistries {
+		args = append(args, fmt.Sprintf("--insecure-registry=%s", registry))
+	}
+
+	return append(args, fmt.Sprintf("--profile=%s", profile))
+}
+
+// saveClusterSpec persists the spec requested for the profile, so that it can be compared with the actual state of
+// the cluster even from a different process
+func saveClusterSpec(path string, spec ClusterSpec) error {
+	content, err := yaml.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("error serializing cluster spec: %w", err)
+	}
+
+	if err = os.WriteFile(path, content, 0o600); err != nil {
+		return fmt.Errorf("error writing cluster spec %s: %w", path, err)
+	}
+
+	return nil
+}
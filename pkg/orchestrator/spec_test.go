@@ -0,0 +1,112 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClusterSpec(t *testing.T) {
+	spec, err := ParseClusterSpec([]byte(`
+kubernetesVersion: 1.30.0
+nodes: 2
+cpus: 4
+memory: 4096
+diskSize: 20000
+driver: docker
+containerRuntime: containerd
+cni: calico
+featureGates:
+  SidecarContainers: true
+  InPlacePodVerticalScaling: false
+extraConfig:
+  - kubelet.max-pods=150
+addons:
+  - ingress
+  - metrics-server
+ports:
+  - "8080:80"
+mount:
+  hostPath: /tmp/fixtures
+  nodePath: /fixtures
+insecureRegistries:
+  - 10.0.0.0/24
+`))
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"start",
+		"--kubernetes-version=1.30.0",
+		"--nodes=2",
+		"--cpus=4",
+		"--memory=4096",
+		"--disk-size=20000mb",
+		"--driver=docker",
+		"--container-runtime=containerd",
+		"--cni=calico",
+		"--feature-gates=InPlacePodVerticalScaling=false,SidecarContainers=true",
+		"--extra-config=kubelet.max-pods=150",
+		"--addons=ingress",
+		"--addons=metrics-server",
+		"--ports=8080:80",
+		"--mount",
+		"--mount-string=/tmp/fixtures:/fixtures",
+		"--insecure-registry=10.0.0.0/24",
+		"--profile=my-profile",
+	}, spec.StartArgs("my-profile"))
+}
+
+func TestParseClusterSpecUnknownField(t *testing.T) {
+	_, err := ParseClusterSpec([]byte("kubernetesVersion: 1.30.0\nnodez: 2\n"))
+	require.Error(t, err)
+}
+
+func TestClusterSpecValidate(t *testing.T) {
+	valid := ClusterSpec{KubernetesVersion: "1.30.0", Nodes: 1, CPUs: 2, Memory: 2048}
+
+	tests := []struct {
+		name    string
+		modify  func(spec *ClusterSpec)
+		wantErr bool
+	}{
+		{name: "Valid spec", modify: func(_ *ClusterSpec) {}, wantErr: false},
+		{name: "CNI manifest path", modify: func(spec *ClusterSpec) { spec.CNI = "/tmp/cni.yaml" }, wantErr: false},
+		{name: "Missing version", modify: func(spec *ClusterSpec) { spec.KubernetesVersion = "" }, wantErr: true},
+		{name: "No nodes", modify: func(spec *ClusterSpec) { spec.Nodes = 0 }, wantErr: true},
+		{name: "Not enough cpus", modify: func(spec *ClusterSpec) { spec.CPUs = 1 }, wantErr: true},
+		{name: "Not enough memory", modify: func(spec *ClusterSpec) { spec.Memory = 1024 }, wantErr: true},
+		{name: "Unknown driver", modify: func(spec *ClusterSpec) { spec.Driver = "lxc" }, wantErr: true},
+		{name: "Unknown runtime", modify: func(spec *ClusterSpec) { spec.ContainerRuntime = "rkt" }, wantErr: true},
+		{name: "Unknown CNI", modify: func(spec *ClusterSpec) { spec.CNI = "weave" }, wantErr: true},
+		{name: "Invalid extra config", modify: func(spec *ClusterSpec) { spec.ExtraConfig = []string{"kubelet-max-pods"} }, wantErr: true},
+		{name: "Unknown component", modify: func(spec *ClusterSpec) { spec.ExtraConfig = []string{"proxy.mode=ipvs"} }, wantErr: true},
+		{name: "Invalid port", modify: func(spec *ClusterSpec) { spec.Ports = []string{"http:80"} }, wantErr: true},
+		{name: "Relative mount", modify: func(spec *ClusterSpec) { spec.Mount = &MountSpec{HostPath: "fixtures", NodePath: "/fixtures"} }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := valid
+			tt.modify(&spec)
+
+			err := spec.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSaveClusterSpec(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	spec := ClusterSpec{KubernetesVersion: "1.30.0", Nodes: 1, CPUs: 2, Memory: 2048, Addons: []string{"ingress"}}
+
+	require.NoError(t, saveClusterSpec(path, spec))
+
+	loaded, err := LoadClusterSpec(path)
+	require.NoError(t, err)
+	require.Equal(t, spec, loaded)
+}
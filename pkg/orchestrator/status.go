@@ -0,0 +1,232 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	componentRunning    = "Running"
+	componentConfigured = "Configured"
+	componentIrrelevant = "Irrelevant"
+)
+
+// NodeStatus represents the state of the components of a node as reported by minikube status
+type NodeStatus struct {
+	Name       string `json:"Name"`
+	Host       string `json:"Host"`
+	Kubelet    string `json:"Kubelet"`
+	APIServer  string `json:"APIServer"`
+	Kubeconfig string `json:"Kubeconfig"`
+	Worker     bool   `json:"Worker"`
+}
+
+// Healthy checks whether all the components of the node are running, workers don't run API server nor kubeconfig
+func (n NodeStatus) Healthy() bool {
+	return n.Host == componentRunning &&
+		n.Kubelet == componentRunning &&
+		(n.APIServer == componentRunning || n.APIServer == componentIrrelevant) &&
+		(n.Kubeconfig == componentConfigured || n.Kubeconfig == componentIrrelevant)
+}
+
+// SpecDrift represents a setting of the cluster that differs from the spec requested when it was created
+type SpecDrift struct {
+	Field     string
+	Requested string
+	Actual    string
+}
+
+// ClusterStatus contains the state of each node of the profile and the drift from the spec requested
+type ClusterStatus struct {
+	Profile string
+	Nodes   []NodeStatus
+	// Drift is empty if the spec of the profile is not known (e.g. the profile was not created by this library)
+	Drift []SpecDrift
+}
+
+// Healthy checks whether all the nodes of the cluster are healthy
+func (cs *ClusterStatus) Healthy() bool {
+	if len(cs.Nodes) == 0 {
+		return false
+	}
+
+	for _, node := range cs.Nodes {
+		if !node.Healthy() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Profile represents a minikube profile as reported by minikube profile list
+type Profile struct {
+	Name   string
+	Status string
+	Valid  bool
+	Config ProfileConfig
+}
+
+// ProfileConfig contains the subset of the profile configuration that can be compared with a ClusterSpec
+type ProfileConfig struct {
+	Driver           string          `json:"Driver"`
+	CPUs             uint            `json:"CPUs"`
+	Memory           uint            `json:"Memory"`
+	DiskSize         uint            `json:"DiskSize"`
+	KubernetesConfig KubernetesSpec  `json:"KubernetesConfig"`
+	Nodes            []ProfileNode   `json:"Nodes"`
+	Addons           map[string]bool `json:"Addons"`
+}
+
+type KubernetesSpec struct {
+	KubernetesVersion string `json:"KubernetesVersion"`
+	ContainerRuntime  string `json:"ContainerRuntime"`
+	CNI               string `json:"CNI"`
+}
+
+type ProfileNode struct {
+	Name         string `json:"Name"`
+	IP           string `json:"IP"`
+	ControlPlane bool   `json:"ControlPlane"`
+	Worker       bool   `json:"Worker"`
+}
+
+// profileList represents the output of minikube profile list -o json
+type profileList struct {
+	Invalid []profileEntry `json:"invalid"`
+	Valid   []profileEntry `json:"valid"`
+}
+
+type profileEntry struct {
+	Name   string        `json:"Name"`
+	Status string        `json:"Status"`
+	Config ProfileConfig `json:"Config"`
+}
+
+// Status returns the state of each node of the profile. If the profile was created with a spec, the drift between
+// the spec and the actual configuration of the profile is reported too
+func (mc *Minikube) Status(ctx context.Context) (*ClusterStatus, error) {
+	// minikube status exits with non-zero codes when any component is not running, rely on the output instead
+	out, errStatus := mc.output(ctx, "status", "--output=json", fmt.Sprintf("--profile=%s", mc.profile))
+
+	nodes, err := parseNodeStatuses(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve status of profile %s: %w", mc.profile, errors.Join(err, errStatus))
+	}
+
+	status := &ClusterStatus{Profile: mc.profile, Nodes: nodes, Drift: []SpecDrift{}}
+
+	spec, err := mc.Spec()
+	if errors.Is(err, os.ErrNotExist) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := mc.Profiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		if profile.Name == mc.profile {
+			status.Drift = specDrift(spec, profile.Config)
+		}
+	}
+
+	return status, nil
+}
+
+// Profiles returns all the minikube profiles of the host, including the invalid ones
+func (mc *Minikube) Profiles(ctx context.Context) ([]Profile, error) {
+	out, err := mc.output(ctx, "profile", "list", "--output=json")
+	if err != nil {
+		return []Profile{}, fmt.Errorf("failed to list profiles: %w", err)
+	}
+
+	return parseProfiles(out)
+}
+
+// parseNodeStatuses parses the output of minikube status -o json, which is an object for single node clusters and a
+// list for multi node clusters
+func parseNodeStatuses(out []byte) ([]NodeStatus, error) {
+	out = bytes.TrimSpace(out)
+
+	if bytes.HasPrefix(out, []byte("[")) {
+		nodes := []NodeStatus{}
+		if err := json.Unmarshal(out, &nodes); err != nil {
+			return []NodeStatus{}, fmt.Errorf("error parsing status: %w", err)
+		}
+		return nodes, nil
+	}
+
+	var node NodeStatus
+	if err := json.Unmarshal(out, &node); err != nil {
+		return []NodeStatus{}, fmt.Errorf("error parsing status: %w", err)
+	}
+
+	return []NodeStatus{node}, nil
+}
+
+// parseProfiles parses the output of minikube profile list -o json
+func parseProfiles(out []byte) ([]Profile, error) {
+	var list profileList
+	if err := json.Unmarshal(out, &list); err != nil {
+		return []Profile{}, fmt.Errorf("error parsing profiles: %w", err)
+	}
+
+	profiles := []Profile{}
+	for _, entry := range list.Valid {
+		profiles = append(profiles, Profile{Name: entry.Name, Status: entry.Status, Valid: true, Config: entry.Config})
+	}
+	for _, entry := range list.Invalid {
+		profiles = append(profiles, Profile{Name: entry.Name, Status: entry.Status, Valid: false, Config: entry.Config})
+	}
+
+	return profiles, nil
+}
+
+// specDrift compares the spec requested with the actual configuration of the profile, only the settings set in the
+// spec are compared
+func specDrift(spec ClusterSpec, config ProfileConfig) []SpecDrift {
+	drift := []SpecDrift{}
+
+	compare := func(field, requested, actual string) {
+		if requested != "" && requested != actual {
+			drift = append(drift, SpecDrift{Field: field, Requested: requested, Actual: actual})
+		}
+	}
+
+	compare("kubernetesVersion", strings.TrimPrefix(spec.KubernetesVersion, "v"), strings.TrimPrefix(config.KubernetesConfig.KubernetesVersion, "v"))
+	compare("nodes", formatUint(spec.Nodes), strconv.Itoa(len(config.Nodes)))
+	compare("cpus", formatUint(spec.CPUs), formatUint(config.CPUs))
+	compare("memory", formatUint(spec.Memory), formatUint(config.Memory))
+	compare("diskSize", formatUint(spec.DiskSize), formatUint(config.DiskSize))
+	compare("driver", spec.Driver, config.Driver)
+	compare("containerRuntime", spec.ContainerRuntime, config.KubernetesConfig.ContainerRuntime)
+	compare("cni", spec.CNI, config.KubernetesConfig.CNI)
+
+	for _, addon := range spec.Addons {
+		if !config.Addons[addon] {
+			drift = append(drift, SpecDrift{Field: fmt.Sprintf("addons.%s", addon), Requested: "enabled", Actual: "disabled"})
+		}
+	}
+
+	return drift
+}
+
+// formatUint formats the number, zero is formatted as empty so that unset values are skipped in the comparison
+func formatUint(n uint) string {
+	if n == 0 {
+		return ""
+	}
+
+	return strconv.FormatUint(uint64(n), 10)
+}
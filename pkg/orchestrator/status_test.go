@@ -0,0 +1,90 @@
+package orchestrator //nolint:testpackage // no need to split test package
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNodeStatuses(t *testing.T) {
+	single := `{"Name":"my-profile","Host":"Running","Kubelet":"Running","APIServer":"Running","Kubeconfig":"Configured","Worker":false}`
+
+	nodes, err := parseNodeStatuses([]byte(single))
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	require.True(t, nodes[0].Healthy())
+
+	multi := `[
+		{"Name":"my-profile","Host":"Running","Kubelet":"Running","APIServer":"Running","Kubeconfig":"Configured","Worker":false},
+		{"Name":"my-profile-m02","Host":"Running","Kubelet":"Stopped","APIServer":"Irrelevant","Kubeconfig":"Irrelevant","Worker":true}
+	]`
+
+	nodes, err = parseNodeStatuses([]byte(multi))
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+
+	status := &ClusterStatus{Nodes: nodes}
+	require.False(t, status.Healthy())
+	require.False(t, nodes[1].Healthy())
+
+	_, err = parseNodeStatuses([]byte(`* Profile "my-profile" not found`))
+	require.Error(t, err)
+}
+
+func TestParseProfiles(t *testing.T) {
+	out := `{
+		"invalid": [{"Name": "broken", "Status": "", "Config": {}}],
+		"valid": [{
+			"Name": "my-profile",
+			"Status": "Running",
+			"Config": {
+				"Driver": "docker",
+				"CPUs": 2,
+				"Memory": 2048,
+				"DiskSize": 20000,
+				"KubernetesConfig": {"KubernetesVersion": "v1.30.0", "ContainerRuntime": "docker", "CNI": ""},
+				"Nodes": [{"Name": "", "IP": "192.168.49.2", "ControlPlane": true, "Worker": true}],
+				"Addons": {"ingress": true}
+			}
+		}]
+	}`
+
+	profiles, err := parseProfiles([]byte(out))
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+
+	require.Equal(t, "my-profile", profiles[0].Name)
+	require.True(t, profiles[0].Valid)
+	require.Equal(t, "v1.30.0", profiles[0].Config.KubernetesConfig.KubernetesVersion)
+	require.Len(t, profiles[0].Config.Nodes, 1)
+	require.False(t, profiles[1].Valid)
+}
+
+func TestSpecDrift(t *testing.T) {
+	config := ProfileConfig{
+		Driver:           "docker",
+		CPUs:             2,
+		Memory:           2048,
+		KubernetesConfig: KubernetesSpec{KubernetesVersion: "v1.30.0", ContainerRuntime: "docker"},
+		Nodes:            []ProfileNode{{ControlPlane: true, Worker: true}},
+		Addons:           map[string]bool{"ingress": true},
+	}
+
+	spec := ClusterSpec{KubernetesVersion: "1.30.0", Nodes: 1, CPUs: 2, Memory: 2048, Addons: []string{"ingress"}}
+	require.Empty(t, specDrift(spec, config))
+
+	spec = ClusterSpec{
+		KubernetesVersion: "1.31.0",
+		Nodes:             2,
+		CPUs:              2,
+		Memory:            2048,
+		ContainerRuntime:  "containerd",
+		Addons:            []string{"ingress", "metrics-server"},
+	}
+	require.Equal(t, []SpecDrift{
+		{Field: "kubernetesVersion", Requested: "1.31.0", Actual: "1.30.0"},
+		{Field: "nodes", Requested: "2", Actual: "1"},
+		{Field: "containerRuntime", Requested: "containerd", Actual: "docker"},
+		{Field: "addons.metrics-server", Requested: "enabled", Actual: "disabled"},
+	}, specDrift(spec, config))
+}
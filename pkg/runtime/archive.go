@@ -0,0 +1,134 @@
+package runtime
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// archiveManifestName is the name of the manifest inside the tarball generated by docker save
+	archiveManifestName = "manifest.json"
+	// archiveMetadataMaxSize limits the size of the entries kept in memory while reading the tarball (manifest
+	// and image configs), layers are never kept in memory
+	archiveMetadataMaxSize = 4 << 20
+)
+
+// archiveManifest represents each of the entries of the manifest.json generated by docker save
+type archiveManifest struct {
+	Config string   `json:"Config"`
+	Layers []string `json:"Layers"`
+}
+
+// imageConfig represents the subset of the image config required to map layers to instructions
+type imageConfig struct {
+	History []struct {
+		CreatedBy  string `json:"created_by,omitempty"`
+		EmptyLayer bool   `json:"empty_layer,omitempty"`
+	} `json:"history"`
+}
+
+// imageArchive contains the layout of an image extracted from the tarball generated by docker save
+type imageArchive struct {
+	// layers contains the path of the layers inside the tarball, ordered from base to top
+	layers []string
+	// instructions contains the instruction that created each of the layers
+	instructions []string
+	// sizes contains the size of every entry of the tarball
+	sizes map[string]int64
+}
+
+// createdBy returns the instruction that created the layer in the given position
+func (ia *imageArchive) createdBy(layer int) string {
+	if layer < len(ia.instructions) {
+		return ia.instructions[layer]
+	}
+
+	return ""
+}
+
+// readImageArchive reads the tarball generated by docker save and calls inspect with the content of every regular
+// entry. Layers can appear before the manifest in the tarball, so inspect is called before knowing which entries
+// are layers
+func readImageArchive(archive io.Reader, inspect func(name string, content io.Reader) error) (*imageArchive, error) {
+	metadata := map[string][]byte{}
+	sizes := map[string]int64{}
+
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading image archive: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		var reader io.Reader = tr
+		if header.Size <= archiveMetadataMaxSize {
+			content, errRead := io.ReadAll(tr)
+			if errRead != nil {
+				return nil, fmt.Errorf("error reading %s from image archive: %w", header.Name, errRead)
+			}
+			metadata[header.Name] = content
+			reader = bytes.NewReader(content)
+		}
+
+		if err = inspect(header.Name, reader); err != nil {
+			return nil, fmt.Errorf("error inspecting %s from image archive: %w", header.Name, err)
+		}
+		sizes[header.Name] = header.Size
+	}
+
+	var manifests []archiveManifest
+	if err := json.Unmarshal(metadata[archiveManifestName], &manifests); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", archiveManifestName, err)
+	}
+
+	if len(manifests) != 1 {
+		return nil, fmt.Errorf("expected a single image in archive, found %d", len(manifests))
+	}
+
+	var config imageConfig
+	if err := json.Unmarshal(metadata[manifests[0].Config], &config); err != nil {
+		return nil, fmt.Errorf("error parsing image config %s: %w", manifests[0].Config, err)
+	}
+
+	for _, layerPath := range manifests[0].Layers {
+		if _, ok := sizes[layerPath]; !ok {
+			return nil, fmt.Errorf("layer %s not found in image archive", layerPath)
+		}
+	}
+
+	// history contains entries for instructions that don't generate layers (ENV, CMD...), skip them
+	instructions := []string{}
+	for _, h := range config.History {
+		if !h.EmptyLayer {
+			instructions = append(instructions, h.CreatedBy)
+		}
+	}
+
+	return &imageArchive{
+		layers:       manifests[0].Layers,
+		instructions: instructions,
+		sizes:        sizes,
+	}, nil
+}
+
+// layerDigest extracts the digest from the layer path, which can be either blobs/sha256/<digest> (OCI layout) or
+// <digest>/layer.tar (legacy layout)
+func layerDigest(layerPath string) string {
+	if strings.HasPrefix(layerPath, "blobs/sha256/") {
+		return "sha256:" + strings.TrimPrefix(layerPath, "blobs/sha256/")
+	}
+
+	return strings.TrimSuffix(layerPath, "/layer.tar")
+}
@@ -0,0 +1,231 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// CacheBuilderPrefix prefixes the name of the buildx builders used for builds with cache import/export. The
+	// default docker driver can't export cache, so a builder with the docker-container driver is created for each
+	// build and removed afterwards
+	CacheBuilderPrefix = "minikube-testing-cache"
+	// CacheBuilderRemoveTimeout is the time given to remove the builder once the build finishes, the context of the
+	// build may be expired by then
+	CacheBuilderRemoveTimeout = time.Minute
+
+	dockerBinary = "docker"
+	// builderHostHashLength is the number of hex characters of the daemon host hash included in the builder name
+	builderHostHashLength = 8
+)
+
+type CacheType string
+
+const (
+	// CacheLocal stores the cache in a local directory that can be saved and restored as a CI artifact
+	CacheLocal CacheType = "local"
+	// CacheRegistry stores the cache as an image in a registry (e.g. localhost:5000/my-image:cache)
+	CacheRegistry CacheType = "registry"
+)
+
+type CacheMode string
+
+const (
+	// CacheModeMin exports only the layers of the final image
+	CacheModeMin CacheMode = "min"
+	// CacheModeMax exports the layers of all the stages, including the intermediate ones
+	CacheModeMax CacheMode = "max"
+)
+
+// CacheConfig represents a single cache source or destination
+type CacheConfig struct {
+	Type CacheType
+	// Ref is the directory for local caches or the image reference for registry caches
+	Ref string
+	// Mode only applies to exports, defaults to CacheModeMax
+	Mode CacheMode
+}
+
+// BuildCache contains the caches imported before the build and the caches exported after the build
+type BuildCache struct {
+	From []CacheConfig
+	To   []CacheConfig
+}
+
+// importFlag generates the value of the --cache-from flag
+func (c CacheConfig) importFlag() (string, error) {
+	switch c.Type {
+	case CacheLocal:
+		return fmt.Sprintf("type=local,src=%s", c.Ref), nil
+	case CacheRegistry:
+		return fmt.Sprintf("type=registry,ref=%s", c.Ref), nil
+	default:
+		return "", fmt.Errorf("unsupported cache type %q", c.Type)
+	}
+}
+
+// exportFlag generates the value of the --cache-to flag
+func (c CacheConfig) exportFlag() (string, error) {
+	mode := c.Mode
+	if mode == "" {
+		mode = CacheModeMax
+	}
+
+	switch c.Type {
+	case CacheLocal:
+		return fmt.Sprintf("type=local,dest=%s,mode=%s", c.Ref, mode), nil
+	case CacheRegistry:
+		return fmt.Sprintf("type=registry,ref=%s,mode=%s", c.Ref, mode), nil
+	default:
+		return "", fmt.Errorf("unsupported cache type %q", c.Type)
+	}
+}
+
+// BuildImageWithCache builds the image with buildx importing and exporting the layer cache. Each build runs in its
+// own builder that is removed together with its BuildKit state once the build finishes, so only the caches provided
+// are used and nothing is shared between builds. The builder uses the host network so that registries exposed in
+// localhost can be reached. The resulting image is loaded into the daemon of the controller
+func (dc *Docker) BuildImageWithCache(ctx context.Context, image, tag string, dockerfile []byte, filesContext []string, cache BuildCache) error {
+	// validate the dockerfile before uploading the build context to the builder
+	if err := checkDockerfile(dockerfile, filesContext); err != nil {
+		return err
+	}
+
+	builder := dc.cacheBuilderName()
+
+	args, err := cacheBuildArgs(builder, fmt.Sprintf("%s:%s", image, tag), cache)
+	if err != nil {
+		return err
+	}
+
+	buf, err := generateBuildContext(dockerfile, filesContext)
+	if err != nil {
+		return fmt.Errorf("error generating build buffer: %w", err)
+	}
+
+	if err = dc.createCacheBuilder(ctx, builder); err != nil {
+		return err
+	}
+	defer dc.removeCacheBuilder(ctx, builder)
+
+	// the build context is sent as a tarball through stdin
+	if err = dc.runDocker(ctx, buf, args...); err != nil {
+		return fmt.Errorf("error building image with cache: %w", err)
+	}
+
+	return nil
+}
+
+// cacheBuilderName generates a random builder name scoped to the daemon of the controller, in the form
+// minikube-testing-cache-<host hash>-<random suffix>. buildx keeps builders in a store shared by all the daemons, the
+// hash makes it clear which daemon a builder (e.g. one leaked by a crashed build) belongs to
+func (dc *Docker) cacheBuilderName() string {
+	hash := sha256.Sum256([]byte(dc.cli.DaemonHost()))
+
+	return fmt.Sprintf("%s-%s-%s",
+		CacheBuilderPrefix,
+		hex.EncodeToString(hash[:])[:builderHostHashLength],
+		strings.Split(uuid.NewString(), "-")[0],
+	)
+}
+
+// cacheBuildArgs generates the arguments of the buildx build command
+func cacheBuildArgs(builder, ref string, cache BuildCache) ([]string, error) {
+	args := []string{
+		"buildx",
+		"build",
+		"--builder", builder,
+		"--tag", ref,
+		"--load",
+	}
+
+	for _, from := range cache.From {
+		// local caches don't exist until the first export (e.g. first CI run), skip them instead of failing
+		if from.Type == CacheLocal {
+			if _, err := os.Stat(from.Ref); errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+		}
+
+		flag, err := from.importFlag()
+		if err != nil {
+			return []string{}, err
+		}
+		args = append(args, "--cache-from", flag)
+	}
+
+	for _, to := range cache.To {
+		flag, err := to.exportFlag()
+		if err != nil {
+			return []string{}, err
+		}
+		args = append(args, "--cache-to", flag)
+	}
+
+	return append(args, "-"), nil
+}
+
+// createCacheBuilder creates a buildx builder with the docker-container driver, the builder uses the host network so
+// that registries exposed in localhost can be reached
+func (dc *Docker) createCacheBuilder(ctx context.Context, builder string) error {
+	err := dc.runDocker(ctx, nil,
+		"buildx",
+		"create",
+		"--name", builder,
+		"--driver", "docker-container",
+		"--driver-opt", "network=host",
+	)
+	if err != nil {
+		return fmt.Errorf("error creating buildx builder %s: %w", builder, err)
+	}
+
+	return nil
+}
+
+// removeCacheBuilder removes the builder together with its BuildKit state, removal is best effort
+func (dc *Docker) removeCacheBuilder(ctx context.Context, builder string) {
+	removeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), CacheBuilderRemoveTimeout)
+	defer cancel()
+
+	_ = dc.runDocker(removeCtx, nil, "buildx", "rm", "--force", builder)
+}
+
+// runDocker executes the docker CLI against the daemon of the controller, the output is included in the error if it
+// fails
+func (dc *Docker) runDocker(ctx context.Context, stdin *bytes.Buffer, args ...string) error {
+	cmd := dc.dockerCommand(ctx, args...)
+
+	out := new(bytes.Buffer)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(out.String()))
+	}
+
+	return nil
+}
+
+// dockerCommand prepares the docker CLI command, the DOCKER_* variables of the controller are added to the
+// environment so that the CLI (and the builders it creates) targets the same daemon as the client
+func (dc *Docker) dockerCommand(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, dockerBinary, args...)
+	if len(dc.env) > 0 {
+		cmd.Env = append(os.Environ(), dc.env...)
+	}
+
+	return cmd
+}
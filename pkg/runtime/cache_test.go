@@ -0,0 +1,98 @@
+package runtime //nolint:testpackage // no need to split test package
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheBuildArgs(t *testing.T) {
+	existing := t.TempDir()
+	missing := filepath.Join(existing, "missing")
+
+	tests := []struct {
+		name     string
+		cache    BuildCache
+		wantArgs []string
+		wantErr  bool
+	}{
+		{
+			name:     "No cache",
+			cache:    BuildCache{},
+			wantArgs: []string{},
+		},
+		{
+			name: "Local cache import and export",
+			cache: BuildCache{
+				From: []CacheConfig{{Type: CacheLocal, Ref: existing}, {Type: CacheLocal, Ref: missing}},
+				To:   []CacheConfig{{Type: CacheLocal, Ref: existing}},
+			},
+			wantArgs: []string{
+				"--cache-from", "type=local,src=" + existing,
+				"--cache-to", "type=local,dest=" + existing + ",mode=max",
+			},
+		},
+		{
+			name: "Registry cache import and export",
+			cache: BuildCache{
+				From: []CacheConfig{{Type: CacheRegistry, Ref: "localhost:5000/my-image:cache"}},
+				To:   []CacheConfig{{Type: CacheRegistry, Ref: "localhost:5000/my-image:cache", Mode: CacheModeMin}},
+			},
+			wantArgs: []string{
+				"--cache-from", "type=registry,ref=localhost:5000/my-image:cache",
+				"--cache-to", "type=registry,ref=localhost:5000/my-image:cache,mode=min",
+			},
+		},
+		{
+			name:    "Unsupported cache type",
+			cache:   BuildCache{To: []CacheConfig{{Type: "gha"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := cacheBuildArgs("my-builder", "my-image:latest", tt.cache)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			base := []string{"buildx", "build", "--builder", "my-builder", "--tag", "my-image:latest", "--load"}
+			require.Equal(t, append(append(base, tt.wantArgs...), "-"), args)
+		})
+	}
+}
+
+func TestCacheBuilderEndpoint(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "unix:///var/run/docker.sock")
+
+	host, err := NewDockerController()
+	require.NoError(t, err)
+	remote, err := NewDockerControllerWithEndpoint(DockerEndpoint{Host: "tcp://192.168.49.2:2376"})
+	require.NoError(t, err)
+
+	// builders are scoped to the daemon, the random suffix keeps builds against the same daemon apart
+	scope := func(builder string) string {
+		return builder[:strings.LastIndex(builder, "-")]
+	}
+
+	hostBuilder, remoteBuilder := host.cacheBuilderName(), remote.cacheBuilderName()
+	require.True(t, strings.HasPrefix(hostBuilder, CacheBuilderPrefix+"-"))
+	require.NotEqual(t, scope(hostBuilder), scope(remoteBuilder))
+	require.Equal(t, scope(remoteBuilder), scope(remote.cacheBuilderName()))
+	require.NotEqual(t, remoteBuilder, remote.cacheBuilderName())
+
+	// the CLI targets the daemon of the controller
+	cmd := remote.dockerCommand(context.Background(), "buildx", "create", "--name", remoteBuilder)
+	require.Equal(t, []string{dockerBinary, "buildx", "create", "--name", remoteBuilder}, cmd.Args)
+	require.Contains(t, cmd.Env, "DOCKER_HOST=tcp://192.168.49.2:2376")
+	require.Contains(t, cmd.Env, "DOCKER_TLS_VERIFY=")
+
+	// controllers from the environment inherit it untouched
+	require.Nil(t, host.dockerCommand(context.Background(), "version").Env)
+}
@@ -18,6 +18,7 @@ import (
 	img "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 )
 
 const DockerfileDefaultName = "Dockerfile"
@@ -30,6 +31,8 @@ type dockerCredentials struct {
 type Docker struct {
 	cli   *client.Client
 	creds dockerCredentials
+	// env contains the DOCKER_* variables passed to the docker CLI, empty when the daemon comes from the environment
+	env []string
 }
 
 func NewDockerController() (*Docker, error) {
@@ -85,6 +88,11 @@ func (dc *Docker) BuildImageWithContextPath(ctx context.Context, image, tag stri
 }
 
 func (dc *Docker) BuildImageWithOptions(ctx context.Context, dockerfile []byte, filesContext []string, buildOptions types.ImageBuildOptions) error {
+	// validate the dockerfile before uploading the build context to the daemon
+	if err := checkDockerfile(dockerfile, filesContext); err != nil {
+		return err
+	}
+
 	// put together the dockerfile and the files required for the build
 	buf, err := generateBuildContext(dockerfile, filesContext)
 	if err != nil {
@@ -130,6 +138,27 @@ func (dc *Docker) PushImage(ctx context.Context, image, tag string) error {
 	return nil
 }
 
+func (dc *Docker) TagImage(ctx context.Context, image, tag, targetImage, targetTag string) error {
+	source, target := fmt.Sprintf("%s:%s", image, tag), fmt.Sprintf("%s:%s", targetImage, targetTag)
+	if err := dc.cli.ImageTag(ctx, source, target); err != nil {
+		return fmt.Errorf("error tagging image %s as %s: %w", source, target, err)
+	}
+
+	return nil
+}
+
+// RemoveImage removes the image from the daemon together with its untagged parents, images that don't exist are
+// ignored
+func (dc *Docker) RemoveImage(ctx context.Context, image, tag string) error {
+	ref := fmt.Sprintf("%s:%s", image, tag)
+	_, err := dc.cli.ImageRemove(ctx, ref, img.RemoveOptions{Force: true, PruneChildren: true})
+	if err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("error removing image %s: %w", ref, err)
+	}
+
+	return nil
+}
+
 // generateBuildContext creates a buffer that contains the Dockerfile body and the dependency files required
 // during the build step
 func generateBuildContext(dockerfile []byte, filesContext []string) (*bytes.Buffer, error) {
@@ -171,7 +200,7 @@ func generateBuildContext(dockerfile []byte, filesContext []string) (*bytes.Buff
 
 		// generate header
 		err = tarBuf.WriteHeader(&tar.Header{
-			Name: filepath.Base(filePath),
+			Name: contextEntryName(filePath),
 			Size: info.Size(),
 		})
 		if err != nil {
@@ -192,6 +221,11 @@ func generateBuildContext(dockerfile []byte, filesContext []string) (*bytes.Buff
 	return buf, nil
 }
 
+// contextEntryName returns the name under which a file is stored inside the build context
+func contextEntryName(filePath string) string {
+	return filepath.Base(filePath)
+}
+
 // buildLogMessage represents the log structure of docker when building an image
 type buildLogMessage struct {
 	Stream      string          `json:"stream,omitempty"`
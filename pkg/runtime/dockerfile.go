@@ -0,0 +1,304 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+const (
+	RuleSyntax             = "syntax"
+	RuleUnknownInstruction = "unknown-instruction"
+	RuleMissingCopySource  = "missing-copy-source"
+	RuleUndefinedStage     = "undefined-stage"
+	RuleLatestTag          = "latest-tag"
+)
+
+const (
+	latestTag  = "latest"
+	scratchImg = "scratch"
+)
+
+// Diagnostic represents a single problem detected in a Dockerfile before the build is sent to the daemon
+type Diagnostic struct {
+	Line     int
+	Severity Severity
+	Rule     string
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("line %d: %s: %s (%s)", d.Line, d.Severity, d.Message, d.Rule)
+}
+
+// DockerfileValidationError is returned when the Dockerfile contains at least one error diagnostic
+type DockerfileValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *DockerfileValidationError) Error() string {
+	msgs := []string{}
+	for _, d := range e.Diagnostics {
+		msgs = append(msgs, d.String())
+	}
+
+	return fmt.Sprintf("invalid dockerfile: %s", strings.Join(msgs, "; "))
+}
+
+// ValidateDockerfile parses the Dockerfile with the BuildKit frontend parser and returns the diagnostics found. The
+// files context must be the same one that is going to be sent to the daemon so that COPY sources can be checked
+func ValidateDockerfile(dockerfile []byte, filesContext []string) []Diagnostic {
+	ast, err := parser.Parse(bytes.NewReader(dockerfile))
+	if err != nil {
+		return []Diagnostic{{
+			Line:     errorLine(err),
+			Severity: SeverityError,
+			Rule:     RuleSyntax,
+			Message:  err.Error(),
+		}}
+	}
+
+	diagnostics := []Diagnostic{}
+	// stages holds the name of the stages declared so far, the position in the slice is the stage index
+	stages := []string{}
+	// declared holds the name of all the stages of the Dockerfile, used to detect forward references
+	declared := declaredStages(ast.AST)
+
+	for _, node := range ast.AST.Children {
+		inst, errInst := instructions.ParseInstruction(node)
+		if errInst != nil {
+			diagnostics = append(diagnostics, instructionDiagnostic(node, errInst))
+			continue
+		}
+
+		switch cmd := inst.(type) {
+		case *instructions.Stage:
+			if d, ok := checkBaseImage(node.StartLine, cmd.BaseName, stages, declared); ok {
+				diagnostics = append(diagnostics, d)
+			}
+			stages = append(stages, strings.ToLower(cmd.Name))
+		case *instructions.CopyCommand:
+			if cmd.From != "" {
+				if d, ok := checkStageReference(node.StartLine, cmd.From, stages, declared); ok {
+					diagnostics = append(diagnostics, d)
+				}
+				continue
+			}
+			diagnostics = append(diagnostics, checkSources(node.StartLine, cmd.SourcePaths, filesContext)...)
+		case *instructions.AddCommand:
+			diagnostics = append(diagnostics, checkSources(node.StartLine, cmd.SourcePaths, filesContext)...)
+		}
+	}
+
+	return diagnostics
+}
+
+// checkDockerfile validates the Dockerfile and returns an error in case any diagnostic has error severity
+func checkDockerfile(dockerfile []byte, filesContext []string) error {
+	failures := []Diagnostic{}
+	for _, d := range ValidateDockerfile(dockerfile, filesContext) {
+		if d.Severity == SeverityError {
+			failures = append(failures, d)
+		}
+	}
+
+	if len(failures) > 0 {
+		return &DockerfileValidationError{Diagnostics: failures}
+	}
+
+	return nil
+}
+
+// instructionDiagnostic turns an instruction parsing error into a diagnostic
+func instructionDiagnostic(node *parser.Node, err error) Diagnostic {
+	var unknown *instructions.UnknownInstructionError
+	if errors.As(err, &unknown) {
+		return Diagnostic{
+			Line:     node.StartLine,
+			Severity: SeverityError,
+			Rule:     RuleUnknownInstruction,
+			Message:  err.Error(),
+		}
+	}
+
+	return Diagnostic{
+		Line:     node.StartLine,
+		Severity: SeverityError,
+		Rule:     RuleSyntax,
+		Message:  err.Error(),
+	}
+}
+
+// declaredStages returns the names of all the stages declared in the Dockerfile
+func declaredStages(ast *parser.Node) []string {
+	names := []string{}
+	for _, node := range ast.Children {
+		if inst, err := instructions.ParseInstruction(node); err == nil {
+			if stage, ok := inst.(*instructions.Stage); ok && stage.Name != "" {
+				names = append(names, strings.ToLower(stage.Name))
+			}
+		}
+	}
+
+	return names
+}
+
+// checkBaseImage verifies that the FROM instruction does not reference a future stage nor an image tagged as latest
+func checkBaseImage(line int, baseName string, stages, declared []string) (Diagnostic, bool) {
+	// skip images that depend on build arguments, they can only be resolved by the daemon
+	if strings.Contains(baseName, "$") || baseName == scratchImg {
+		return Diagnostic{}, false
+	}
+
+	if slices.Contains(stages, strings.ToLower(baseName)) {
+		return Diagnostic{}, false
+	}
+
+	if slices.Contains(declared, strings.ToLower(baseName)) {
+		return Diagnostic{
+			Line:     line,
+			Severity: SeverityError,
+			Rule:     RuleUndefinedStage,
+			Message:  fmt.Sprintf("stage %s is referenced before being defined", baseName),
+		}, true
+	}
+
+	// images pinned by digest are never resolved through the tag
+	if strings.Contains(baseName, "@") {
+		return Diagnostic{}, false
+	}
+
+	tag := ""
+	// the tag separator is the last colon after the last slash (registry can contain a port)
+	if idx := strings.LastIndex(baseName, ":"); idx > strings.LastIndex(baseName, "/") {
+		tag = baseName[idx+1:]
+	}
+
+	if tag == "" || tag == latestTag {
+		return Diagnostic{
+			Line:     line,
+			Severity: SeverityWarning,
+			Rule:     RuleLatestTag,
+			Message:  fmt.Sprintf("base image %s resolves to the latest tag, pin a specific version", baseName),
+		}, true
+	}
+
+	return Diagnostic{}, false
+}
+
+// checkStageReference verifies that COPY --from points to a stage declared before. References that look like image
+// names (contain registry, tag or digest separators) are left for the daemon to resolve. Bare names that are not
+// stages of the Dockerfile are valid image references (e.g. --from=busybox), so they are only reported as warnings
+func checkStageReference(line int, from string, stages, declared []string) (Diagnostic, bool) {
+	if strings.Contains(from, "$") || strings.ContainsAny(from, ":/@") {
+		return Diagnostic{}, false
+	}
+
+	if idx, err := strconv.Atoi(from); err == nil {
+		if idx >= 0 && idx < len(stages) {
+			return Diagnostic{}, false
+		}
+		return Diagnostic{
+			Line:     line,
+			Severity: SeverityError,
+			Rule:     RuleUndefinedStage,
+			Message:  fmt.Sprintf("stage index %d is not defined before this instruction", idx),
+		}, true
+	}
+
+	if slices.Contains(stages, strings.ToLower(from)) {
+		return Diagnostic{}, false
+	}
+
+	if slices.Contains(declared, strings.ToLower(from)) {
+		return Diagnostic{
+			Line:     line,
+			Severity: SeverityError,
+			Rule:     RuleUndefinedStage,
+			Message:  fmt.Sprintf("stage %s is referenced before being defined", from),
+		}, true
+	}
+
+	return Diagnostic{
+		Line:     line,
+		Severity: SeverityWarning,
+		Rule:     RuleUndefinedStage,
+		Message:  fmt.Sprintf("%s is not a stage of the dockerfile, it will be resolved as an image", from),
+	}, true
+}
+
+// checkSources verifies that every COPY/ADD source is present in the build context
+func checkSources(line int, sources []string, filesContext []string) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	// the dockerfile is always written into the build context next to the files, see generateBuildContext
+	entries := []string{DockerfileDefaultName}
+	for _, filePath := range filesContext {
+		entries = append(entries, contextEntryName(filePath))
+	}
+
+	for _, src := range sources {
+		// remote sources and build arguments can't be checked locally
+		if strings.Contains(src, "$") || strings.Contains(src, "://") || strings.HasPrefix(src, "git@") {
+			continue
+		}
+
+		if !matchesContext(src, entries) {
+			diagnostics = append(diagnostics, Diagnostic{
+				Line:     line,
+				Severity: SeverityError,
+				Rule:     RuleMissingCopySource,
+				Message:  fmt.Sprintf("source %s is not present in the build context", src),
+			})
+		}
+	}
+
+	return diagnostics
+}
+
+// matchesContext checks whether the source path matches any of the entries of the build context
+func matchesContext(src string, entries []string) bool {
+	src = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(src)), "/")
+
+	// the whole context is always a valid source, even if it's empty
+	if src == "" {
+		return true
+	}
+
+	for _, entry := range entries {
+		if entry == src || strings.HasPrefix(entry, src+"/") {
+			return true
+		}
+
+		if matched, err := path.Match(src, entry); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// errorLine extracts the line number from a parser error, returns 0 if the error does not contain location
+func errorLine(err error) int {
+	var loc *parser.ErrorLocation
+	if errors.As(err, &loc) && len(loc.Locations) > 0 && len(loc.Locations[0]) > 0 {
+		return loc.Locations[0][0].Start.Line
+	}
+
+	return 0
+}
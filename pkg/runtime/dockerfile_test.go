@@ -0,0 +1,124 @@
+package runtime //nolint:testpackage // no need to split test package
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDockerfile(t *testing.T) {
+	filesContext := []string{"_fixture/main.go", "_fixture/go.mod", "_fixture/go.sum"}
+
+	tests := []struct {
+		name       string
+		dockerfile string
+		files      []string
+		wantRules  []string
+		wantLines  []int
+	}{
+		{
+			name: "Valid multi stage dockerfile",
+			dockerfile: `FROM golang:1.23 AS builder
+COPY go.mod go.sum ./
+COPY *.go ./
+FROM alpine:3.18
+COPY --from=builder /app/main .
+COPY --from=0 /app/main .`,
+			files:     filesContext,
+			wantRules: []string{},
+			wantLines: []int{},
+		},
+		{
+			name:       "Unknown instruction",
+			dockerfile: "FROM alpine:3.18\nCOPPY main.go .\n",
+			files:      filesContext,
+			wantRules:  []string{RuleUnknownInstruction},
+			wantLines:  []int{2},
+		},
+		{
+			name:       "Syntax error",
+			dockerfile: "FROM alpine:3.18\nEXPOSE\n",
+			files:      filesContext,
+			wantRules:  []string{RuleSyntax},
+			wantLines:  []int{2},
+		},
+		{
+			name:       "Missing copy source",
+			dockerfile: "FROM alpine:3.18\nCOPY go.mod config.yaml ./\n",
+			files:      filesContext,
+			wantRules:  []string{RuleMissingCopySource},
+			wantLines:  []int{2},
+		},
+		{
+			name:       "Missing copy source without build context",
+			dockerfile: "FROM alpine:3.18\nCOPY . .\nADD main.go .\n",
+			files:      []string{},
+			wantRules:  []string{RuleMissingCopySource},
+			wantLines:  []int{3},
+		},
+		{
+			name:       "Copy dockerfile from the build context",
+			dockerfile: "FROM alpine:3.18\nCOPY Dockerfile /x\n",
+			files:      []string{},
+			wantRules:  []string{},
+			wantLines:  []int{},
+		},
+		{
+			name:       "Undefined stage reference",
+			dockerfile: "FROM alpine:3.18\nCOPY --from=builder /app/main .\nCOPY --from=3 /app/main .\n",
+			files:      filesContext,
+			wantRules:  []string{RuleUndefinedStage, RuleUndefinedStage},
+			wantLines:  []int{2, 3},
+		},
+		{
+			name:       "Forward copy stage reference",
+			dockerfile: "FROM alpine:3.18\nCOPY --from=builder /app/main .\nFROM golang:1.23 AS builder\n",
+			files:      filesContext,
+			wantRules:  []string{RuleUndefinedStage},
+			wantLines:  []int{2},
+		},
+		{
+			name:       "Forward stage reference",
+			dockerfile: "FROM builder\nFROM golang:1.23 AS builder\n",
+			files:      filesContext,
+			wantRules:  []string{RuleUndefinedStage},
+			wantLines:  []int{1},
+		},
+		{
+			name:       "Latest and untagged base images",
+			dockerfile: "FROM alpine:latest\nFROM localhost:5000/busybox\nFROM scratch\n",
+			files:      filesContext,
+			wantRules:  []string{RuleLatestTag, RuleLatestTag},
+			wantLines:  []int{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, lines := []string{}, []int{}
+			for _, d := range ValidateDockerfile([]byte(tt.dockerfile), tt.files) {
+				rules = append(rules, d.Rule)
+				lines = append(lines, d.Line)
+			}
+
+			require.Equal(t, tt.wantRules, rules)
+			require.Equal(t, tt.wantLines, lines)
+		})
+	}
+}
+
+func TestCheckDockerfileFixture(t *testing.T) {
+	dockerfile, err := os.ReadFile("_fixture/Dockerfile")
+	require.NoError(t, err)
+
+	require.NoError(t, checkDockerfile(dockerfile, []string{"_fixture/main.go", "_fixture/go.mod", "_fixture/go.sum"}))
+
+	// bare image references in COPY --from are valid, they must not block the build
+	require.NoError(t, checkDockerfile([]byte("FROM alpine:3.18\nCOPY --from=busybox /bin/busybox /bin/\n"), []string{}))
+
+	err = checkDockerfile(dockerfile, []string{})
+	var validationErr *DockerfileValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Len(t, validationErr.Diagnostics, 2)
+}
@@ -0,0 +1,60 @@
+package runtime
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/docker/docker/client"
+)
+
+const (
+	dockerCACert     = "ca.pem"
+	dockerClientCert = "cert.pem"
+	dockerClientKey  = "key.pem"
+)
+
+// DockerEndpoint contains the connection settings of a docker daemon other than the one configured in the
+// environment, e.g. the daemon that runs inside a minikube node
+type DockerEndpoint struct {
+	// Host is the address of the daemon (e.g. tcp://192.168.49.2:2376)
+	Host string
+	// CertPath is the directory that contains ca.pem, cert.pem and key.pem, TLS is disabled if empty
+	CertPath string
+}
+
+// env returns the environment variables that point the docker CLI to the endpoint. The TLS variables are always set,
+// otherwise the ones inherited from the environment would enable TLS against a plain endpoint
+func (e DockerEndpoint) env() []string {
+	env := []string{fmt.Sprintf("%s=%s", client.EnvOverrideHost, e.Host)}
+	if e.CertPath == "" {
+		return append(env,
+			fmt.Sprintf("%s=", client.EnvOverrideCertPath),
+			fmt.Sprintf("%s=", client.EnvTLSVerify),
+		)
+	}
+
+	return append(env,
+		fmt.Sprintf("%s=%s", client.EnvOverrideCertPath, e.CertPath),
+		fmt.Sprintf("%s=1", client.EnvTLSVerify),
+	)
+}
+
+// NewDockerControllerWithEndpoint creates a controller bound to the daemon of the endpoint instead of the one
+// configured in the environment, images built are stored directly in that daemon
+func NewDockerControllerWithEndpoint(endpoint DockerEndpoint) (*Docker, error) {
+	opts := []client.Opt{client.WithHost(endpoint.Host), client.WithAPIVersionNegotiation()}
+	if endpoint.CertPath != "" {
+		opts = append(opts, client.WithTLSClientConfig(
+			filepath.Join(endpoint.CertPath, dockerCACert),
+			filepath.Join(endpoint.CertPath, dockerClientCert),
+			filepath.Join(endpoint.CertPath, dockerClientKey),
+		))
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to docker endpoint %s: %w", endpoint.Host, err)
+	}
+
+	return &Docker{cli: cli, creds: dockerCredentials{enabled: false}, env: endpoint.env()}, nil
+}
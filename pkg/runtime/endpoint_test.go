@@ -0,0 +1,32 @@
+package runtime //nolint:testpackage // no need to split test package
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerEndpointEnv(t *testing.T) {
+	endpoint := DockerEndpoint{Host: "tcp://192.168.49.2:2376", CertPath: "/home/user/.minikube/certs"}
+	require.Equal(t, []string{
+		"DOCKER_HOST=tcp://192.168.49.2:2376",
+		"DOCKER_CERT_PATH=/home/user/.minikube/certs",
+		"DOCKER_TLS_VERIFY=1",
+	}, endpoint.env())
+
+	// TLS settings inherited from the environment are cleared for plain endpoints
+	require.Equal(t, []string{
+		"DOCKER_HOST=unix:///var/run/docker.sock",
+		"DOCKER_CERT_PATH=",
+		"DOCKER_TLS_VERIFY=",
+	}, DockerEndpoint{Host: "unix:///var/run/docker.sock"}.env())
+}
+
+func TestNewDockerControllerWithEndpoint(t *testing.T) {
+	dc, err := NewDockerControllerWithEndpoint(DockerEndpoint{Host: "tcp://192.168.49.2:2376"})
+	require.NoError(t, err)
+	require.Equal(t, "tcp://192.168.49.2:2376", dc.cli.DaemonHost())
+
+	_, err = NewDockerControllerWithEndpoint(DockerEndpoint{Host: "tcp://192.168.49.2:2376", CertPath: t.TempDir()})
+	require.Error(t, err)
+}
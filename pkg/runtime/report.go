@@ -0,0 +1,184 @@
+package runtime
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	KB int64 = 1 << 10
+	MB       = KB << 10
+	GB       = MB << 10
+)
+
+// LayerReport contains the size of a single layer together with the instruction that created it
+type LayerReport struct {
+	Digest         string
+	CreatedBy      string
+	Size           int64
+	CompressedSize int64
+}
+
+// ImageReport contains the size of the image and the breakdown of its layers, ordered from base to top
+type ImageReport struct {
+	Image          string
+	Layers         []LayerReport
+	Size           int64
+	CompressedSize int64
+}
+
+// Budget sets size limits for an image, zero values disable the limit
+type Budget struct {
+	MaxImageSize           int64
+	MaxCompressedImageSize int64
+	MaxLayerSize           int64
+	MaxLayers              int
+}
+
+// BudgetExceededError is returned when an image report does not fit into the budget
+type BudgetExceededError struct {
+	Image      string
+	Violations []string
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("image %s exceeds budget: %s", e.Image, strings.Join(e.Violations, "; "))
+}
+
+// ImageSizeReport generates the size report for an image available in the local daemon. Compressed sizes are
+// calculated by gzipping each layer, which is what registries store
+func (dc *Docker) ImageSizeReport(ctx context.Context, image, tag string) (*ImageReport, error) {
+	ref := fmt.Sprintf("%s:%s", image, tag)
+
+	archive, err := dc.cli.ImageSave(ctx, []string{ref})
+	if err != nil {
+		return nil, fmt.Errorf("error saving image %s: %w", ref, err)
+	}
+	defer archive.Close()
+
+	report, err := parseImageArchive(archive)
+	if err != nil {
+		return nil, fmt.Errorf("error analyzing image %s: %w", ref, err)
+	}
+	report.Image = ref
+
+	return report, nil
+}
+
+// CheckBudget verifies that the image report fits into the budget, returns a BudgetExceededError with all the
+// violations otherwise
+func (r *ImageReport) CheckBudget(budget Budget) error {
+	violations := []string{}
+
+	if budget.MaxImageSize > 0 && r.Size > budget.MaxImageSize {
+		violations = append(violations, fmt.Sprintf("image size %s > %s", formatSize(r.Size), formatSize(budget.MaxImageSize)))
+	}
+
+	if budget.MaxCompressedImageSize > 0 && r.CompressedSize > budget.MaxCompressedImageSize {
+		violations = append(violations, fmt.Sprintf("compressed image size %s > %s", formatSize(r.CompressedSize), formatSize(budget.MaxCompressedImageSize)))
+	}
+
+	if budget.MaxLayers > 0 && len(r.Layers) > budget.MaxLayers {
+		violations = append(violations, fmt.Sprintf("number of layers %d > %d", len(r.Layers), budget.MaxLayers))
+	}
+
+	if budget.MaxLayerSize > 0 {
+		for _, layer := range r.Layers {
+			if layer.Size > budget.MaxLayerSize {
+				violations = append(violations, fmt.Sprintf("layer created by %q size %s > %s", layer.CreatedBy, formatSize(layer.Size), formatSize(budget.MaxLayerSize)))
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &BudgetExceededError{Image: r.Image, Violations: violations}
+	}
+
+	return nil
+}
+
+// String renders the report as a table, one layer per line
+func (r *ImageReport) String() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s: %s (%s compressed), %d layers\n", r.Image, formatSize(r.Size), formatSize(r.CompressedSize), len(r.Layers))
+	for _, layer := range r.Layers {
+		fmt.Fprintf(&sb, "%10s %10s  %s\n", formatSize(layer.Size), formatSize(layer.CompressedSize), layer.CreatedBy)
+	}
+
+	return sb.String()
+}
+
+// parseImageArchive reads the tarball generated by docker save and builds the image report
+func parseImageArchive(archive io.Reader) (*ImageReport, error) {
+	compressed := map[string]int64{}
+
+	img, err := readImageArchive(archive, func(name string, content io.Reader) error {
+		size, errCompress := compressedSize(content)
+		if errCompress != nil {
+			return fmt.Errorf("error compressing %s: %w", name, errCompress)
+		}
+		compressed[name] = size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImageReport{Layers: []LayerReport{}}
+	for i, layerPath := range img.layers {
+		report.Layers = append(report.Layers, LayerReport{
+			Digest:         layerDigest(layerPath),
+			CreatedBy:      img.createdBy(i),
+			Size:           img.sizes[layerPath],
+			CompressedSize: compressed[layerPath],
+		})
+		report.Size += img.sizes[layerPath]
+		report.CompressedSize += compressed[layerPath]
+	}
+
+	return report, nil
+}
+
+// countingWriter counts the bytes written without storing them
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// compressedSize returns the size of the content once gzipped
+func compressedSize(content io.Reader) (int64, error) {
+	counter := &countingWriter{}
+	gz := gzip.NewWriter(counter)
+
+	if _, err := io.Copy(gz, content); err != nil {
+		return 0, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return 0, err
+	}
+
+	return counter.n, nil
+}
+
+// formatSize turns a number of bytes into a human-readable size
+func formatSize(size int64) string {
+	switch {
+	case size >= GB:
+		return fmt.Sprintf("%.2fGB", float64(size)/float64(GB))
+	case size >= MB:
+		return fmt.Sprintf("%.2fMB", float64(size)/float64(MB))
+	case size >= KB:
+		return fmt.Sprintf("%.2fKB", float64(size)/float64(KB))
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
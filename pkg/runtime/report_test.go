@@ -0,0 +1,99 @@
+package runtime //nolint:testpackage // no need to split test package
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// generateImageArchive creates a tarball with the same layout as docker save, layers are written before the
+// manifest to reproduce the order used by the daemon
+func generateImageArchive(t *testing.T, files map[string][]byte, order []string) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+
+	for _, name := range order {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(files[name])), Mode: 0o644, Typeflag: tar.TypeReg}))
+		_, err := tw.Write(files[name])
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	return buf
+}
+
+func TestParseImageArchive(t *testing.T) {
+	files := map[string][]byte{
+		"blobs/sha256/aaa": bytes.Repeat([]byte("a"), 2048),
+		"blobs/sha256/bbb": bytes.Repeat([]byte("b"), 512),
+		"blobs/sha256/ccc": []byte(`{"history":[
+			{"created_by":"ADD rootfs.tar /"},
+			{"created_by":"ENV PATH=/bin","empty_layer":true},
+			{"created_by":"COPY main /app/main"}
+		]}`),
+		"manifest.json": []byte(`[{"Config":"blobs/sha256/ccc","Layers":["blobs/sha256/aaa","blobs/sha256/bbb"]}]`),
+	}
+
+	archive := generateImageArchive(t, files, []string{"blobs/sha256/aaa", "blobs/sha256/bbb", "blobs/sha256/ccc", "manifest.json"})
+	report, err := parseImageArchive(archive)
+	require.NoError(t, err)
+
+	require.Len(t, report.Layers, 2)
+	require.Equal(t, "sha256:aaa", report.Layers[0].Digest)
+	require.Equal(t, "ADD rootfs.tar /", report.Layers[0].CreatedBy)
+	require.Equal(t, int64(2048), report.Layers[0].Size)
+	require.Equal(t, "COPY main /app/main", report.Layers[1].CreatedBy)
+	require.Equal(t, int64(2560), report.Size)
+	// repeated content compresses well
+	require.Less(t, report.CompressedSize, report.Size)
+}
+
+func TestParseImageArchiveMissingLayer(t *testing.T) {
+	files := map[string][]byte{
+		"config.json":   []byte(`{"history":[{"created_by":"ADD rootfs.tar /"}]}`),
+		"manifest.json": []byte(`[{"Config":"config.json","Layers":["aaa/layer.tar"]}]`),
+	}
+
+	_, err := parseImageArchive(generateImageArchive(t, files, []string{"config.json", "manifest.json"}))
+	require.Error(t, err)
+}
+
+func TestCheckBudget(t *testing.T) {
+	report := &ImageReport{
+		Image: "my-image:latest",
+		Layers: []LayerReport{
+			{CreatedBy: "ADD rootfs.tar /", Size: 80 * MB, CompressedSize: 30 * MB},
+			{CreatedBy: "COPY main /app/main", Size: 20 * MB, CompressedSize: 10 * MB},
+		},
+		Size:           100 * MB,
+		CompressedSize: 40 * MB,
+	}
+
+	tests := []struct {
+		name           string
+		budget         Budget
+		wantViolations int
+	}{
+		{name: "Empty budget", budget: Budget{}, wantViolations: 0},
+		{name: "Within budget", budget: Budget{MaxImageSize: 200 * MB, MaxLayerSize: 100 * MB, MaxLayers: 2}, wantViolations: 0},
+		{name: "Image too big", budget: Budget{MaxImageSize: 50 * MB, MaxCompressedImageSize: 20 * MB}, wantViolations: 2},
+		{name: "Layer too big", budget: Budget{MaxLayerSize: 50 * MB}, wantViolations: 1},
+		{name: "Too many layers", budget: Budget{MaxLayers: 1}, wantViolations: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := report.CheckBudget(tt.budget)
+			if tt.wantViolations == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var budgetErr *BudgetExceededError
+			require.ErrorAs(t, err, &budgetErr)
+			require.Len(t, budgetErr.Violations, tt.wantViolations)
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package runtime
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	img "github.com/docker/docker/api/types/image"
+	"github.com/google/uuid"
+)
+
+const (
+	// reproducibilityImage is the repository used to tag the images built during the reproducibility verification
+	reproducibilityImage = "minikube-testing-reproducibility"
+	// reproducibilityBuilds is the number of builds compared during the reproducibility verification
+	reproducibilityBuilds = 2
+)
+
+const (
+	ReasonAdded   = "added"
+	ReasonRemoved = "removed"
+	ReasonContent = "content"
+	ReasonModTime = "mtime"
+	ReasonMode    = "mode"
+	ReasonOwner   = "owner"
+	ReasonLink    = "link"
+)
+
+// FileDifference represents a file that differs between two builds of the same layer
+type FileDifference struct {
+	Path    string
+	Reasons []string
+}
+
+// LayerDifference represents a layer whose digest differs between two builds
+type LayerDifference struct {
+	Index     int
+	CreatedBy string
+	DigestA   string
+	DigestB   string
+	Files     []FileDifference
+}
+
+// ReproducibilityReport contains the result of building the same image twice and comparing the layers
+type ReproducibilityReport struct {
+	Reproducible bool
+	Layers       []LayerDifference
+}
+
+func (r *ReproducibilityReport) String() string {
+	if r.Reproducible {
+		return "build is reproducible"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("build is not reproducible:\n")
+	for _, layer := range r.Layers {
+		fmt.Fprintf(&sb, "layer %d (%s): %s != %s\n", layer.Index, layer.CreatedBy, layer.DigestA, layer.DigestB)
+		for _, file := range layer.Files {
+			fmt.Fprintf(&sb, "  %s: %s\n", file.Path, strings.Join(file.Reasons, ", "))
+		}
+	}
+
+	return sb.String()
+}
+
+// VerifyReproducibleBuild builds the same image twice with caching disabled and compares the layers of both builds.
+// The tags of the build options are replaced by temporary tags, which are removed once the verification finishes
+func (dc *Docker) VerifyReproducibleBuild(ctx context.Context, dockerfile []byte, filesContext []string, buildOptions types.ImageBuildOptions) (*ReproducibilityReport, error) {
+	builds := []*layerIndex{}
+	id := uuid.NewString()
+
+	for i := range reproducibilityBuilds {
+		ref := fmt.Sprintf("%s:%s-%d", reproducibilityImage, id, i)
+
+		options := buildOptions
+		options.Tags = []string{ref}
+		options.NoCache = true
+		options.Remove = true
+
+		if err := dc.BuildImageWithOptions(ctx, dockerfile, filesContext, options); err != nil {
+			return nil, fmt.Errorf("error during build %d: %w", i, err)
+		}
+
+		index, err := dc.indexImage(ctx, ref)
+		// the image is only needed for indexing, removal is best effort to avoid hiding the build result
+		_, _ = dc.cli.ImageRemove(ctx, ref, img.RemoveOptions{Force: true, PruneChildren: true})
+		if err != nil {
+			return nil, err
+		}
+
+		builds = append(builds, index)
+	}
+
+	return compareLayerIndexes(builds[0], builds[1]), nil
+}
+
+// layerFile contains the attributes of a file inside a layer that can make two builds differ
+type layerFile struct {
+	hash    string
+	modTime int64
+	mode    int64
+	owner   string
+	link    string
+}
+
+// layerIndex contains the files of each of the layers of an image
+type layerIndex struct {
+	digests      []string
+	instructions []string
+	files        []map[string]layerFile
+}
+
+// indexImage saves the image from the daemon and indexes the files of each layer
+func (dc *Docker) indexImage(ctx context.Context, ref string) (*layerIndex, error) {
+	archive, err := dc.cli.ImageSave(ctx, []string{ref})
+	if err != nil {
+		return nil, fmt.Errorf("error saving image %s: %w", ref, err)
+	}
+	defer archive.Close()
+
+	index, err := parseLayerIndex(archive)
+	if err != nil {
+		return nil, fmt.Errorf("error indexing image %s: %w", ref, err)
+	}
+
+	return index, nil
+}
+
+// parseLayerIndex reads the tarball generated by docker save and indexes the files of each layer
+func parseLayerIndex(archive io.Reader) (*layerIndex, error) {
+	entries := map[string]map[string]layerFile{}
+
+	ia, err := readImageArchive(archive, func(name string, content io.Reader) error {
+		files, errIndex := indexLayerFiles(content)
+		// entries that are not tarballs (manifest, configs...) are not layers, no need to index them
+		if errIndex == nil {
+			entries[name] = files
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	index := &layerIndex{}
+	for i, layerPath := range ia.layers {
+		files, ok := entries[layerPath]
+		if !ok {
+			return nil, fmt.Errorf("layer %s is not a valid tarball", layerPath)
+		}
+
+		index.digests = append(index.digests, layerDigest(layerPath))
+		index.instructions = append(index.instructions, ia.createdBy(i))
+		index.files = append(index.files, files)
+	}
+
+	return index, nil
+}
+
+// indexLayerFiles reads a layer tarball and returns the attributes of each of its files
+func indexLayerFiles(layer io.Reader) (map[string]layerFile, error) {
+	files := map[string]layerFile{}
+
+	tr := tar.NewReader(layer)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		hash := sha256.New()
+		if _, err = io.Copy(hash, tr); err != nil {
+			return nil, err
+		}
+
+		files[header.Name] = layerFile{
+			hash:    hex.EncodeToString(hash.Sum(nil)),
+			modTime: header.ModTime.Unix(),
+			mode:    header.Mode,
+			owner:   fmt.Sprintf("%d:%d", header.Uid, header.Gid),
+			link:    header.Linkname,
+		}
+	}
+
+	return files, nil
+}
+
+// compareLayerIndexes compares the layers of two builds and reports the files that differ in each layer
+func compareLayerIndexes(a, b *layerIndex) *ReproducibilityReport {
+	report := &ReproducibilityReport{Reproducible: true, Layers: []LayerDifference{}}
+
+	for i := range max(len(a.digests), len(b.digests)) {
+		diff := LayerDifference{Index: i, Files: []FileDifference{}}
+		filesA, filesB := map[string]layerFile{}, map[string]layerFile{}
+
+		if i < len(a.digests) {
+			diff.DigestA, diff.CreatedBy, filesA = a.digests[i], a.instructions[i], a.files[i]
+		}
+		if i < len(b.digests) {
+			diff.DigestB, diff.CreatedBy, filesB = b.digests[i], b.instructions[i], b.files[i]
+		}
+
+		if diff.DigestA == diff.DigestB {
+			continue
+		}
+
+		diff.Files = compareLayerFiles(filesA, filesB)
+		report.Reproducible = false
+		report.Layers = append(report.Layers, diff)
+	}
+
+	return report
+}
+
+// compareLayerFiles returns the files that differ between two builds of the same layer, sorted by path
+func compareLayerFiles(a, b map[string]layerFile) []FileDifference {
+	diffs := []FileDifference{}
+
+	for path, fileA := range a {
+		fileB, ok := b[path]
+		if !ok {
+			diffs = append(diffs, FileDifference{Path: path, Reasons: []string{ReasonRemoved}})
+			continue
+		}
+
+		reasons := []string{}
+		if fileA.hash != fileB.hash {
+			reasons = append(reasons, ReasonContent)
+		}
+		if fileA.modTime != fileB.modTime {
+			reasons = append(reasons, ReasonModTime)
+		}
+		if fileA.mode != fileB.mode {
+			reasons = append(reasons, ReasonMode)
+		}
+		if fileA.owner != fileB.owner {
+			reasons = append(reasons, ReasonOwner)
+		}
+		if fileA.link != fileB.link {
+			reasons = append(reasons, ReasonLink)
+		}
+
+		if len(reasons) > 0 {
+			diffs = append(diffs, FileDifference{Path: path, Reasons: reasons})
+		}
+	}
+
+	for path := range b {
+		if _, ok := a[path]; !ok {
+			diffs = append(diffs, FileDifference{Path: path, Reasons: []string{ReasonAdded}})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+
+	return diffs
+}
@@ -0,0 +1,80 @@
+package runtime //nolint:testpackage // no need to split test package
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// generateLayer creates a layer tarball with the given files, all of them with the same modification time
+func generateLayer(t *testing.T, files map[string]string, modTime time.Time) []byte {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(content)), Mode: 0o755, ModTime: modTime, Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	return buf.Bytes()
+}
+
+func TestParseLayerIndex(t *testing.T) {
+	files := map[string][]byte{
+		"blobs/sha256/aaa": generateLayer(t, map[string]string{"etc/os-release": "alpine"}, time.Unix(0, 0)),
+		"blobs/sha256/ccc": []byte(`{"history":[{"created_by":"ADD rootfs.tar /"}]}`),
+		"manifest.json":    []byte(`[{"Config":"blobs/sha256/ccc","Layers":["blobs/sha256/aaa"]}]`),
+	}
+
+	archive := generateImageArchive(t, files, []string{"blobs/sha256/aaa", "blobs/sha256/ccc", "manifest.json"})
+	index, err := parseLayerIndex(archive)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"sha256:aaa"}, index.digests)
+	require.Equal(t, []string{"ADD rootfs.tar /"}, index.instructions)
+	require.Contains(t, index.files[0], "etc/os-release")
+}
+
+func TestCompareLayerIndexes(t *testing.T) {
+	base := map[string]layerFile{"etc/os-release": {hash: "1"}}
+
+	a := &layerIndex{
+		digests:      []string{"sha256:aaa", "sha256:bbb"},
+		instructions: []string{"ADD rootfs.tar /", "RUN go build -o main ."},
+		files: []map[string]layerFile{base, {
+			"app/main":    {hash: "2", modTime: 1},
+			"app/version": {hash: "3", modTime: 1},
+			"app/config":  {hash: "4", modTime: 1},
+		}},
+	}
+
+	b := &layerIndex{
+		digests:      []string{"sha256:aaa", "sha256:ddd"},
+		instructions: []string{"ADD rootfs.tar /", "RUN go build -o main ."},
+		files: []map[string]layerFile{base, {
+			"app/main":    {hash: "5", modTime: 2},
+			"app/version": {hash: "3", modTime: 1},
+			"app/build":   {hash: "6", modTime: 1},
+		}},
+	}
+
+	report := compareLayerIndexes(a, b)
+	require.False(t, report.Reproducible)
+	require.Len(t, report.Layers, 1)
+
+	layer := report.Layers[0]
+	require.Equal(t, 1, layer.Index)
+	require.Equal(t, "RUN go build -o main .", layer.CreatedBy)
+	require.Equal(t, []FileDifference{
+		{Path: "app/build", Reasons: []string{ReasonAdded}},
+		{Path: "app/config", Reasons: []string{ReasonRemoved}},
+		{Path: "app/main", Reasons: []string{ReasonContent, ReasonModTime}},
+	}, layer.Files)
+
+	require.True(t, compareLayerIndexes(a, a).Reproducible)
+}
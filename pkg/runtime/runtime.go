@@ -11,7 +11,14 @@ type Runtime interface {
 	BuildImageWithOptions(ctx context.Context, dockerfile []byte, filesContext []string, buildOptions types.ImageBuildOptions) error
 	BuildImageWithContextPath(ctx context.Context, image, tag string, dockerfile []byte, contextPath string, args ...string) error
 
+	BuildImageWithCache(ctx context.Context, image, tag string, dockerfile []byte, filesContext []string, cache BuildCache) error
+
 	BuildMultiStageImage(ctx context.Context) error
 
+	TagImage(ctx context.Context, image, tag, targetImage, targetTag string) error
+	RemoveImage(ctx context.Context, image, tag string) error
 	PushImage(ctx context.Context, image, tag string) error
+
+	ImageSizeReport(ctx context.Context, image, tag string) (*ImageReport, error)
+	VerifyReproducibleBuild(ctx context.Context, dockerfile []byte, filesContext []string, buildOptions types.ImageBuildOptions) (*ReproducibilityReport, error)
 }